@@ -0,0 +1,214 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+const (
+	timeout = 5 * time.Second
+
+	// sigV4Service is the signing name of amazon managed prometheus (whose
+	// alertmanager api requires the requests to be signed).
+	sigV4Service = "aps"
+
+	maxErrorBody = 1024
+)
+
+var (
+	ErrAlertmanagerInvalidURL      = errors.New("invalid alertmanager url")
+	ErrAlertmanagerInvalidResponse = errors.New("invalid alertmanager response")
+	ErrAlertmanagerRequestFailed   = errors.New("alertmanager request failed")
+)
+
+// Client talks to alertmanager's v2 api.
+type Client struct {
+	externalURL string
+	http        *http.Client
+	url         string
+
+	credentials aws.CredentialsProvider
+	region      string
+	signer      *v4.Signer
+}
+
+func New(cfg *config.Alertmanager) (*Client, error) {
+	for _, raw := range []string{cfg.URL, cfg.ExternalURL} {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w",
+				ErrAlertmanagerInvalidURL, err,
+			)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("%w: %s",
+				ErrAlertmanagerInvalidURL, raw,
+			)
+		}
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: url is empty",
+			ErrAlertmanagerInvalidURL,
+		)
+	}
+
+	c := &Client{
+		externalURL: strings.TrimSuffix(cfg.ExternalURL, "/"),
+		http:        &http.Client{Timeout: timeout},
+		url:         strings.TrimSuffix(cfg.URL, "/"),
+	}
+
+	if cfg.SigV4Region != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		awscfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.credentials = awscfg.Credentials
+		c.region = cfg.SigV4Region
+		c.signer = v4.NewSigner()
+	}
+
+	return c, nil
+}
+
+type silenceMatcher struct {
+	IsEqual bool   `json:"isEqual"`
+	IsRegex bool   `json:"isRegex"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+}
+
+type postableSilence struct {
+	Comment   string           `json:"comment"`
+	CreatedBy string           `json:"createdBy"`
+	EndsAt    time.Time        `json:"endsAt"`
+	Matchers  []silenceMatcher `json:"matchers"`
+	StartsAt  time.Time        `json:"startsAt"`
+}
+
+// CreateSilence posts the silence to alertmanager and returns the ID that
+// alertmanager assigned to it.
+//
+// See: https://github.com/prometheus/alertmanager/blob/main/api/v2/openapi.yaml
+func (c *Client) CreateSilence(ctx context.Context, s *types.Silence) (string, error) {
+	matchers, err := matcher.ParseAll(s.Matchers)
+	if err != nil {
+		return "", types.Permanent(err)
+	}
+	silence := postableSilence{
+		Comment:   s.Comment,
+		CreatedBy: s.CreatedBy,
+		EndsAt:    s.EndsAt,
+		Matchers:  make([]silenceMatcher, 0, len(matchers)),
+		StartsAt:  s.StartsAt,
+	}
+	for _, m := range matchers {
+		silence.Matchers = append(silence.Matchers, silenceMatcher{
+			IsEqual: m.Type == matcher.TypeEqual || m.Type == matcher.TypeRegexp,
+			IsRegex: m.Type == matcher.TypeRegexp || m.Type == matcher.TypeNotRegexp,
+			Name:    m.Name,
+			Value:   m.Value,
+		})
+	}
+	if silence.Comment == "" {
+		// alertmanager insists on it
+		silence.Comment = "-"
+	}
+
+	body, err := json.Marshal(silence)
+	if err != nil {
+		return "", types.Permanent(err)
+	}
+
+	var res struct {
+		SilenceID string `json:"silenceID"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v2/silences", body, &res); err != nil {
+		return "", err
+	}
+	if res.SilenceID == "" {
+		return "", types.Permanent(fmt.Errorf("%w: silence id is missing",
+			ErrAlertmanagerInvalidResponse,
+		))
+	}
+	return res.SilenceID, nil
+}
+
+// SilenceURL returns the link to the silence in alertmanager's ui (or an
+// empty string if the external url of alertmanager is not configured).
+func (c *Client) SilenceURL(id string) string {
+	if c.externalURL == "" {
+		return ""
+	}
+	return c.externalURL + "/#/silences/" + url.PathEscape(id)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, res interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return types.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if c.signer != nil {
+		creds, err := c.credentials.Retrieve(ctx)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(body)
+		if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), sigV4Service, c.region, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		err := fmt.Errorf("%w: %s %s: %s: %s",
+			ErrAlertmanagerRequestFailed, method, path, resp.Status, strings.TrimSpace(string(msg)),
+		)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return types.Transient(err)
+		}
+		return types.Permanent(err)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return types.Permanent(fmt.Errorf("%w: %w",
+			ErrAlertmanagerInvalidResponse, err,
+		))
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Alertmanager
+		err  error
+	}{
+		{name: "valid", cfg: config.Alertmanager{URL: "http://alertmanager:9093/", ExternalURL: "https://am.example.com"}},
+		{name: "no url", cfg: config.Alertmanager{ExternalURL: "https://am.example.com"}, err: ErrAlertmanagerInvalidURL},
+		{name: "no scheme", cfg: config.Alertmanager{URL: "alertmanager:9093"}, err: ErrAlertmanagerInvalidURL},
+		{name: "invalid external url", cfg: config.Alertmanager{URL: "http://alertmanager:9093", ExternalURL: "/am"}, err: ErrAlertmanagerInvalidURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&tt.cfg)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("want %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSilenceURL(t *testing.T) {
+	c, err := New(&config.Alertmanager{URL: "http://alertmanager:9093", ExternalURL: "https://am.example.com/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := c.SilenceURL("a/b"), "https://am.example.com/#/silences/a%2Fb"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	c, err = New(&config.Alertmanager{URL: "http://alertmanager:9093"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.SilenceURL("id"); got != "" {
+		t.Errorf("want no link without the external url, got %q", got)
+	}
+}
+
+func TestCreateSilence(t *testing.T) {
+	startsAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	silence := &types.Silence{
+		CreatedBy: "jane",
+		EndsAt:    startsAt.Add(time.Hour),
+		Matchers:  []string{`alertname="A"`, `env!="dev"`, `team=~"infra|web"`, `instance!~"test-.*"`},
+		StartsAt:  startsAt,
+	}
+
+	var got postableSilence
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/silences" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"silenceID":"s1"}`))
+	}))
+	defer srv.Close()
+
+	c, err := New(&config.Alertmanager{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	id, err := c.CreateSilence(context.Background(), silence)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "s1" {
+		t.Errorf("want the silence id %q, got %q", "s1", id)
+	}
+
+	want := postableSilence{
+		Comment:   "-",
+		CreatedBy: "jane",
+		EndsAt:    silence.EndsAt,
+		Matchers: []silenceMatcher{
+			{IsEqual: true, IsRegex: false, Name: "alertname", Value: "A"},
+			{IsEqual: false, IsRegex: false, Name: "env", Value: "dev"},
+			{IsEqual: true, IsRegex: true, Name: "team", Value: "infra|web"},
+			{IsEqual: false, IsRegex: true, Name: "instance", Value: "test-.*"},
+		},
+		StartsAt: startsAt,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want the silence posted as %+v, got %+v", want, got)
+	}
+}
+
+func TestCreateSilenceFailures(t *testing.T) {
+	silence := &types.Silence{
+		EndsAt:   time.Now().Add(time.Hour),
+		Matchers: []string{`alertname="A"`},
+		StartsAt: time.Now(),
+	}
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		matchers []string
+		want     error
+	}{
+		{name: "unavailable", status: http.StatusServiceUnavailable, want: types.ErrTransient},
+		{name: "rate limited", status: http.StatusTooManyRequests, want: types.ErrTransient},
+		{name: "rejected", status: http.StatusBadRequest, body: "invalid matcher", want: types.ErrPermanent},
+		{name: "no silence id", status: http.StatusOK, body: `{}`, want: types.ErrPermanent},
+		{name: "not json", status: http.StatusOK, body: `<html>`, want: types.ErrPermanent},
+		{name: "invalid matchers", status: http.StatusOK, matchers: []string{`alertname`}, want: types.ErrPermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c, err := New(&config.Alertmanager{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			s := *silence
+			if tt.matchers != nil {
+				s.Matchers = tt.matchers
+			}
+			_, err = c.CreateSilence(context.Background(), &s)
+			if err == nil {
+				t.Fatalf("want the failure")
+			}
+			if kind := types.Kind(err); kind != tt.want {
+				t.Errorf("want the error classified as %v, got %v: %v", tt.want, kind, err)
+			}
+		})
+	}
+}
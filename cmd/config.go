@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/secret"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+var (
+	configFile = ""
+	rawConfig  []byte
+)
+
+var (
+	ErrConfigFileFailedToRead = errors.New("failed to read configuration file")
+)
+
+// readConfigFile reads the configuration from the local file, from s3 object
+// (`s3://bucket/key`), or from `CONFIG` key of the secret in secrets manager.
+func readConfigFile(source string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "arn:aws:secretsmanager:"):
+		s, err := secret.AWS(source)
+		if err != nil {
+			return nil, err
+		}
+		raw, exists := s["CONFIG"]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s: %s",
+				ErrSecretMissingKey, source, "CONFIG",
+			)
+		}
+		return []byte(raw), nil
+
+	case strings.HasPrefix(source, "s3://"):
+		return secret.S3(source)
+
+	default:
+		return os.ReadFile(source)
+	}
+}
+
+// loadConfigFile reads the configuration file (if one was specified).
+func loadConfigFile() error {
+	if configFile == "" {
+		return nil
+	}
+	raw, err := readConfigFile(configFile)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w",
+			ErrConfigFileFailedToRead, configFile, err,
+		)
+	}
+	rawConfig = raw
+	return nil
+}
+
+// applyConfigFile decodes the configuration file on top of cfg, and then
+// re-applies the flags that were explicitly set (via cli or via env), so
+// that those take the precedence over the file.  It must be called once, by
+// the command (after all flags, both the app's and the command's ones, have
+// been parsed).
+func applyConfigFile(clictx *cli.Context, cfg *config.Config) error {
+	if rawConfig == nil {
+		return nil
+	}
+
+	flags := slices.Clone(clictx.App.Flags)
+	for _, c := range clictx.Lineage() {
+		if c.Command != nil {
+			flags = append(flags, c.Command.Flags...)
+		}
+	}
+
+	// the flags write directly into cfg, so we must remember their values
+	// before the file overwrites them
+	explicit := make(map[string]string)
+	for _, flag := range flags {
+		name := flag.Names()[0]
+		if clictx.IsSet(name) {
+			explicit[name] = fmt.Sprint(clictx.Value(name))
+		}
+	}
+
+	if err := config.Decode(rawConfig, cfg); err != nil {
+		return fmt.Errorf("%s: %w", configFile, err)
+	}
+
+	for name, value := range explicit {
+		if err := clictx.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupLogging replaces the global logger with the one configured by cfg.
+func setupLogging(cfg *config.Config) error {
+	l, err := logutils.NewLogger(&cfg.Log)
+	if err != nil {
+		return fmt.Errorf("%w: %w",
+			ErrFailedToSetupLogging, err,
+		)
+	}
+	zap.ReplaceGlobals(l)
+	return nil
+}
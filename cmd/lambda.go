@@ -1,28 +1,66 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	awslambda "github.com/aws/aws-lambda-go/lambda"
+	"github.com/flashbots/prometheus-sns-lambda-slack/alertmanager"
 	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+	"github.com/flashbots/prometheus-sns-lambda-slack/escalation"
+	"github.com/flashbots/prometheus-sns-lambda-slack/filter"
+	"github.com/flashbots/prometheus-sns-lambda-slack/inhibit"
 	"github.com/flashbots/prometheus-sns-lambda-slack/processor"
+	"github.com/flashbots/prometheus-sns-lambda-slack/publisher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/ratelimit"
+	"github.com/flashbots/prometheus-sns-lambda-slack/router"
 	"github.com/flashbots/prometheus-sns-lambda-slack/secret"
 	"github.com/urfave/cli/v2"
 )
 
 var (
-	defaultSlackToken = "" // can be injected at build-time
-	rawIgnoreRules    = ""
+	defaultSlackToken     = "" // can be injected at build-time
+	rawDropRules          = ""
+	rawEscalationPolicies = ""
+	rawIgnoreRules        = ""
+	rawInhibitRules       = ""
+	rawReminders          = ""
+	rawSlackMentions      = ""
+	rawSlackRoutes        = ""
+
+	rawEscalationAckReactions = ""
+
+	rawThreadIdentityLabels = ""
+
+	remindersMention = false
+)
+
+var (
+	dbBackends = []string{db.BackendDynamoDB, db.BackendBolt, db.BackendMemory}
 )
 
 var (
-	ErrDynamoDBMissing       = errors.New("dynamo db name must be configured")
-	ErrSecretMissingKey      = errors.New("secret manager misses key")
-	ErrSlackAPITokenMissing  = errors.New("slack API token must be provided")
-	ErrSlackChannelIDMissing = errors.New("slack channel ID must be configured")
-	ErrSlackChannelMissing   = errors.New("slack channel name must be configured")
+	ErrDBBackendInvalid        = errors.New("invalid db backend")
+	ErrDBPathMissing           = errors.New("db path must be configured")
+	ErrDropRulesInvalid        = errors.New("invalid drop rules")
+	ErrEscalationInvalid       = errors.New("invalid escalation policies")
+	ErrFlappingInvalid         = errors.New("invalid flapping detection settings")
+	ErrInhibitRulesInvalid     = errors.New("invalid inhibit rules")
+	ErrDynamoDBMissing         = errors.New("dynamo db name must be configured")
+	ErrLockLeaseInvalid        = errors.New("lock lease must be positive")
+	ErrMessageRetentionInvalid = errors.New("message retention must be longer than lock lease")
+	ErrRemindersInvalid        = errors.New("invalid reminders")
+	ErrSecretMissingKey        = errors.New("secret manager misses key")
+	ErrSlackRoutesInvalid      = errors.New("invalid slack routes")
+	ErrSlackAPITokenMissing    = errors.New("slack API token must be provided")
+	ErrSlackChannelIDMissing   = errors.New("slack channel ID must be configured")
+	ErrSlackChannelMissing     = errors.New("slack channel name must be configured")
+	ErrSlackLayoutInvalid      = errors.New("invalid slack layout")
+	ErrSlackMentionsInvalid    = errors.New("invalid slack mentions")
 )
 
 func CommandLambda(cfg *config.Config) *cli.Command {
@@ -30,19 +68,204 @@ func CommandLambda(cfg *config.Config) *cli.Command {
 		Name:  "lambda",
 		Usage: "Run lambda handler (default)",
 
-		Flags: []cli.Flag{
+		Flags: append(dbFlags(cfg), []cli.Flag{
 			&cli.StringFlag{
-				Destination: &cfg.Processor.DynamoDBName,
-				EnvVars:     []string{"DYNAMODB_NAME"},
-				Name:        "dynamo-db-name",
-				Usage:       "the name of Dynamo DB to keep the track of alerts",
+				Destination: &cfg.Alertmanager.URL,
+				EnvVars:     []string{"ALERTMANAGER_URL"},
+				Name:        "alertmanager-url",
+				Usage:       "the url of alertmanager's api to create the silences requested from slack in (instead of the db)",
+			},
+
+			&cli.StringFlag{
+				Destination: &cfg.Alertmanager.ExternalURL,
+				EnvVars:     []string{"ALERTMANAGER_EXTERNAL_URL"},
+				Name:        "alertmanager-external-url",
+				Usage:       "the url of alertmanager's ui to link the silences to",
+			},
+
+			&cli.StringFlag{
+				Destination: &cfg.Alertmanager.SigV4Region,
+				EnvVars:     []string{"ALERTMANAGER_SIGV4_REGION"},
+				Name:        "alertmanager-sigv4-region",
+				Usage:       "sign the requests to alertmanager with AWS SigV4 for the region (required by amazon managed prometheus)",
+			},
+
+			&cli.BoolFlag{
+				Destination: &cfg.Processor.Digest,
+				EnvVars:     []string{"DIGEST"},
+				Name:        "digest",
+				Usage:       "post one digest per alertmanager's group (with the alerts as the replies in its thread) instead of one thread per alert",
+			},
+
+			&cli.StringFlag{
+				Destination: &rawDropRules,
+				EnvVars:     []string{"DROP_RULES"},
+				Name:        "drop-rules",
+				Usage:       "json-encoded list of rules that drop the alerts based on their labels and annotations",
+			},
+
+			&cli.BoolFlag{
+				Destination: &cfg.Processor.DropRulesDryRun,
+				EnvVars:     []string{"DROP_RULES_DRY_RUN"},
+				Name:        "drop-rules-dry-run",
+				Usage:       "only log the alerts that would be dropped by drop rules (instead of dropping them)",
+			},
+
+			&cli.StringFlag{
+				Destination: &rawEscalationAckReactions,
+				EnvVars:     []string{"ESCALATION_ACK_REACTIONS"},
+				Name:        "escalation-ack-reactions",
+				Usage:       "comma-separated list of emoji reactions to the first message of the thread that acknowledge the alert (and stop its escalation)",
+				Value:       "eyes",
+			},
+
+			&cli.StringFlag{
+				Destination: &rawEscalationPolicies,
+				EnvVars:     []string{"ESCALATION_POLICIES"},
+				Name:        "escalation-policies",
+				Usage:       "json-encoded list of policies that escalate the alerts nobody acknowledges based on their labels",
+			},
+
+			&cli.IntFlag{
+				Destination: &cfg.Processor.Flapping.Transitions,
+				EnvVars:     []string{"FLAPPING_TRANSITIONS"},
+				Name:        "flapping-transitions",
+				Usage:       "how many status changes within flapping window make the alert flapping (0 disables the detection)",
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.Flapping.Window,
+				EnvVars:     []string{"FLAPPING_WINDOW"},
+				Name:        "flapping-window",
+				Usage:       "the window the status changes of the alert are counted within",
+				Value:       time.Hour,
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.Flapping.StablePeriod,
+				EnvVars:     []string{"FLAPPING_STABLE_PERIOD"},
+				Name:        "flapping-stable-period",
+				Usage:       "for how long the flapping alert has to keep its status to be considered settled",
+				Value:       30 * time.Minute,
+			},
+
+			&cli.StringFlag{
+				Destination: &rawInhibitRules,
+				EnvVars:     []string{"INHIBIT_RULES"},
+				Name:        "inhibit-rules",
+				Usage:       "json-encoded list of rules that mute (or fold into the threads of the firing ones) the alerts that depend on others",
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.LockLease,
+				EnvVars:     []string{"LOCK_LEASE"},
+				Name:        "lock-lease",
+				Usage:       "for how long the message stays locked while being published (after that another instance can take over)",
+				Value:       30 * time.Second,
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.MessageRetention,
+				EnvVars:     []string{"MESSAGE_RETENTION"},
+				Name:        "message-retention",
+				Usage:       "for how long the published messages are remembered (the duplicates that arrive later get published again)",
+				Value:       24 * time.Hour,
+			},
+
+			&cli.IntFlag{
+				Destination: &cfg.Processor.RateLimits.Alertname.Burst,
+				EnvVars:     []string{"RATE_LIMIT_ALERTNAME"},
+				Name:        "rate-limit-alertname",
+				Usage:       "how many alerts with the same alertname can be posted per rate limit period (0 disables the limit)",
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.RateLimits.Alertname.Period,
+				EnvVars:     []string{"RATE_LIMIT_ALERTNAME_PERIOD"},
+				Name:        "rate-limit-alertname-period",
+				Usage:       "the period of the per-alertname rate limit",
+				Value:       time.Minute,
+			},
+
+			&cli.IntFlag{
+				Destination: &cfg.Processor.RateLimits.Channel.Burst,
+				EnvVars:     []string{"RATE_LIMIT_CHANNEL"},
+				Name:        "rate-limit-channel",
+				Usage:       "how many alerts can be posted into the same channel per rate limit period (0 disables the limit)",
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.RateLimits.Channel.Period,
+				EnvVars:     []string{"RATE_LIMIT_CHANNEL_PERIOD"},
+				Name:        "rate-limit-channel-period",
+				Usage:       "the period of the per-channel rate limit",
+				Value:       time.Minute,
+			},
+
+			&cli.IntFlag{
+				Destination: &cfg.Processor.RateLimits.Global.Burst,
+				EnvVars:     []string{"RATE_LIMIT_GLOBAL"},
+				Name:        "rate-limit-global",
+				Usage:       "how many alerts can be posted in total per rate limit period (0 disables the limit)",
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.RateLimits.Global.Period,
+				EnvVars:     []string{"RATE_LIMIT_GLOBAL_PERIOD"},
+				Name:        "rate-limit-global-period",
+				Usage:       "the period of the global rate limit",
+				Value:       time.Minute,
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Processor.RateLimits.ReportInterval,
+				EnvVars:     []string{"RATE_LIMIT_REPORT_INTERVAL"},
+				Name:        "rate-limit-report-interval",
+				Usage:       "how often the count of the alerts suppressed by the rate limits is reported into the channel",
+				Value:       5 * time.Minute,
+			},
+
+			&cli.StringFlag{
+				Destination: &rawReminders,
+				EnvVars:     []string{"REMINDERS"},
+				Name:        "reminders",
+				Usage:       "comma-separated list of severity=interval pairs to remind about the alerts that keep firing every interval (* stands for any severity), e.g. critical=1h,*=24h",
+			},
+
+			&cli.BoolFlag{
+				Destination: &remindersMention,
+				EnvVars:     []string{"REMINDERS_MENTION"},
+				Name:        "reminders-mention",
+				Usage:       "mention the same people in the reminders the first message of the thread did",
 			},
 
 			&cli.StringFlag{
 				Destination: &rawIgnoreRules,
 				EnvVars:     []string{"IGNORE_RULES"},
 				Name:        "ignore-rules",
-				Usage:       "comma-separated list of rules to ignore",
+				Usage:       "comma-separated list of rules to ignore (same as drop rule with alertname matcher)",
+			},
+
+			&cli.StringFlag{
+				Destination: &rawThreadIdentityLabels,
+				EnvVars:     []string{"THREAD_IDENTITY_LABELS"},
+				Name:        "thread-identity-labels",
+				Usage:       "comma-separated list of labels that identify the thread of the alert (all labels if empty)",
+			},
+
+			&cli.BoolFlag{
+				Destination: &cfg.Processor.Thread.IncludeStartsAt,
+				EnvVars:     []string{"THREAD_INCLUDE_STARTS_AT"},
+				Name:        "thread-include-starts-at",
+				Usage:       "whether the start time of the alert identifies its thread (if not, re-fired alerts join the existing threads)",
+				Value:       true,
+			},
+
+			&cli.BoolFlag{
+				Destination: &cfg.Processor.SilenceNotes,
+				EnvVars:     []string{"SILENCE_NOTES"},
+				Name:        "silence-notes",
+				Usage:       "post a note into the thread of the silenced alert (once per silence)",
 			},
 
 			&cli.StringFlag{
@@ -59,33 +282,89 @@ func CommandLambda(cfg *config.Config) *cli.Command {
 				Usage:       "slack channel ID to publish the alerts to",
 			},
 
+			&cli.StringFlag{
+				Destination: &cfg.Slack.Layout,
+				EnvVars:     []string{"SLACK_LAYOUT"},
+				Name:        "slack-layout",
+				Usage:       "the layout of slack messages (" + publisher.LayoutAttachments + ", " + publisher.LayoutBlocks + ")",
+				Value:       publisher.LayoutAttachments,
+			},
+
+			&cli.StringFlag{
+				Destination: &rawSlackMentions,
+				EnvVars:     []string{"SLACK_MENTIONS"},
+				Name:        "slack-mentions",
+				Usage:       "json-encoded list of users and user groups to mention in the first message of the thread based on the labels",
+			},
+
+			&cli.BoolFlag{
+				Destination: &cfg.Slack.QuietResolved,
+				EnvVars:     []string{"SLACK_QUIET_RESOLVED"},
+				Name:        "slack-quiet-resolved",
+				Usage:       "do not post the resolved alerts into their threads (only update the root messages)",
+			},
+
+			&cli.StringFlag{
+				Destination: &rawSlackRoutes,
+				EnvVars:     []string{"SLACK_ROUTES"},
+				Name:        "slack-routes",
+				Usage:       "json-encoded list of routes that direct the alerts to other slack channels based on their labels",
+			},
+
+			&cli.StringFlag{
+				Destination: &cfg.Slack.SigningSecret,
+				EnvVars:     []string{"SLACK_SIGNING_SECRET"},
+				Name:        "slack-signing-secret",
+				Usage:       "slack app's signing secret to verify the interactions with (enables the buttons on the root messages)",
+			},
+
 			&cli.StringFlag{
 				Destination: &cfg.Slack.Token,
 				EnvVars:     []string{"SLACK_TOKEN"},
 				Name:        "slack-token",
 				Usage:       "slack API token to be used",
 			},
-		},
+		}...),
+
+		Before: func(clictx *cli.Context) error {
+			// apply configuration file (if applicable)
+			if err := applyConfigFile(clictx, cfg); err != nil {
+				return err
+			}
+			if err := setupLogging(cfg); err != nil {
+				return err
+			}
 
-		Before: func(_ *cli.Context) error {
 			// read secrets (if applicable)
-			if strings.HasPrefix(cfg.Slack.Token, "arn:aws:secretsmanager:") {
-				s, err := secret.AWS(cfg.Slack.Token)
-				if err != nil {
-					return err
-				}
-				slackToken, exists := s["SLACK_TOKEN"]
-				if !exists {
-					return fmt.Errorf("%w: %s: %s",
-						ErrSecretMissingKey, cfg.Slack.Token, "SLACK_TOKEN",
-					)
-				}
-				cfg.Slack.Token = slackToken
+			if err := resolveSecret(&cfg.Slack.Token, "SLACK_TOKEN"); err != nil {
+				return err
+			}
+			if err := resolveSecret(&cfg.Slack.SigningSecret, "SLACK_SIGNING_SECRET"); err != nil {
+				return err
 			}
 
 			// validate inputs
-			if cfg.Processor.DynamoDBName == "" {
-				return ErrDynamoDBMissing
+			if err := validateDB(cfg); err != nil {
+				return err
+			}
+			if cfg.Processor.LockLease <= 0 {
+				return ErrLockLeaseInvalid
+			}
+			if cfg.Processor.MessageRetention <= cfg.Processor.LockLease {
+				return ErrMessageRetentionInvalid
+			}
+			if cfg.Processor.Flapping.Transitions < 0 {
+				return fmt.Errorf("%w: transitions must not be negative",
+					ErrFlappingInvalid,
+				)
+			}
+			if cfg.Processor.Flapping.Transitions > 0 && (cfg.Processor.Flapping.Window <= 0 || cfg.Processor.Flapping.StablePeriod <= 0) {
+				return fmt.Errorf("%w: window and stable period must be positive",
+					ErrFlappingInvalid,
+				)
+			}
+			if err := ratelimit.Validate(&cfg.Processor.RateLimits); err != nil {
+				return err
 			}
 			if cfg.Slack.Token == "" {
 				if defaultSlackToken == "" {
@@ -99,8 +378,26 @@ func CommandLambda(cfg *config.Config) *cli.Command {
 			if cfg.Slack.ChannelID == "" {
 				return ErrSlackChannelIDMissing
 			}
+			switch cfg.Slack.Layout {
+			case publisher.LayoutAttachments, publisher.LayoutBlocks:
+				// ok
+			default:
+				return fmt.Errorf("%w: %s",
+					ErrSlackLayoutInvalid, cfg.Slack.Layout,
+				)
+			}
+
+			// validate alertmanager's settings
+			if cfg.Alertmanager.URL != "" || cfg.Alertmanager.ExternalURL != "" || cfg.Alertmanager.SigV4Region != "" {
+				if _, err := alertmanager.New(&cfg.Alertmanager); err != nil {
+					return err
+				}
+			}
 
 			// parse the list of ignored rules
+			if clictx.IsSet("ignore-rules") {
+				cfg.Processor.IgnoreRules = make(config.StringSet)
+			}
 			for _, r := range strings.Split(rawIgnoreRules, ",") {
 				if r == "" {
 					continue
@@ -108,6 +405,123 @@ func CommandLambda(cfg *config.Config) *cli.Command {
 				cfg.Processor.IgnoreRules[strings.TrimSpace(r)] = struct{}{}
 			}
 
+			// parse the drop rules
+			if rawDropRules != "" {
+				if err := json.Unmarshal([]byte(rawDropRules), &cfg.Processor.DropRules); err != nil {
+					return fmt.Errorf("%w: %w",
+						ErrDropRulesInvalid, err,
+					)
+				}
+			}
+			if _, err := filter.New(&cfg.Processor); err != nil {
+				return err
+			}
+
+			// parse the inhibit rules
+			if rawInhibitRules != "" {
+				if err := json.Unmarshal([]byte(rawInhibitRules), &cfg.Processor.InhibitRules); err != nil {
+					return fmt.Errorf("%w: %w",
+						ErrInhibitRulesInvalid, err,
+					)
+				}
+			}
+			if _, err := inhibit.New(&cfg.Processor); err != nil {
+				return err
+			}
+
+			// parse the escalation policies
+			if rawEscalationPolicies != "" {
+				if err := json.Unmarshal([]byte(rawEscalationPolicies), &cfg.Processor.Escalation.Policies); err != nil {
+					return fmt.Errorf("%w: %w",
+						ErrEscalationInvalid, err,
+					)
+				}
+			}
+			if clictx.IsSet("escalation-ack-reactions") || cfg.Processor.Escalation.AckReactions == nil {
+				cfg.Processor.Escalation.AckReactions = []string{}
+				for _, r := range strings.Split(rawEscalationAckReactions, ",") {
+					if r = strings.Trim(strings.TrimSpace(r), ":"); r != "" {
+						cfg.Processor.Escalation.AckReactions = append(cfg.Processor.Escalation.AckReactions, r)
+					}
+				}
+			}
+			if _, err := escalation.New(&cfg.Processor.Escalation); err != nil {
+				return err
+			}
+
+			// parse the list of thread identity labels
+			if clictx.IsSet("thread-identity-labels") {
+				cfg.Processor.Thread.IdentityLabels = []string{}
+				for _, l := range strings.Split(rawThreadIdentityLabels, ",") {
+					if l = strings.TrimSpace(l); l != "" {
+						cfg.Processor.Thread.IdentityLabels = append(cfg.Processor.Thread.IdentityLabels, l)
+					}
+				}
+			}
+
+			// parse the reminders
+			if clictx.IsSet("reminders") {
+				cfg.Processor.Reminders = []*config.Reminder{}
+				for _, r := range strings.Split(rawReminders, ",") {
+					if r = strings.TrimSpace(r); r == "" {
+						continue
+					}
+					severity, rawInterval, found := strings.Cut(r, "=")
+					if !found {
+						return fmt.Errorf("%w: %s",
+							ErrRemindersInvalid, r,
+						)
+					}
+					interval, err := time.ParseDuration(rawInterval)
+					if err != nil {
+						return fmt.Errorf("%w: %w",
+							ErrRemindersInvalid, err,
+						)
+					}
+					if severity == "*" {
+						severity = ""
+					}
+					cfg.Processor.Reminders = append(cfg.Processor.Reminders, &config.Reminder{
+						RepeatInterval: interval,
+						Severity:       severity,
+					})
+				}
+			}
+			for _, r := range cfg.Processor.Reminders {
+				if clictx.IsSet("reminders-mention") {
+					r.Mention = remindersMention
+				}
+				if r.RepeatInterval <= 0 {
+					return fmt.Errorf("%w: repeat interval must be positive",
+						ErrRemindersInvalid,
+					)
+				}
+			}
+
+			// parse the mentions
+			if rawSlackMentions != "" {
+				if err := json.Unmarshal([]byte(rawSlackMentions), &cfg.Slack.Mentions); err != nil {
+					return fmt.Errorf("%w: %w",
+						ErrSlackMentionsInvalid, err,
+					)
+				}
+			}
+			if _, err := publisher.NewMentions(cfg.Slack.Mentions); err != nil {
+				return err
+			}
+
+			// parse the routes
+			if rawSlackRoutes != "" {
+				if err := json.Unmarshal([]byte(rawSlackRoutes), &cfg.Slack.Routes); err != nil {
+					return fmt.Errorf("%w: %w",
+						ErrSlackRoutesInvalid, err,
+					)
+				}
+			}
+			if _, err := router.New(&cfg.Slack); err != nil {
+				return err
+			}
+
 			return nil
 		},
 
@@ -116,8 +530,76 @@ func CommandLambda(cfg *config.Config) *cli.Command {
 			if err != nil {
 				return err
 			}
-			awslambda.Start(p.Lambda)
+			awslambda.Start(p.LambdaHandler)
 			return nil
 		},
 	}
 }
+
+// resolveSecret replaces the value with the key of the secret in secrets
+// manager if the value is the ARN of the secret.
+func resolveSecret(value *string, key string) error {
+	if !strings.HasPrefix(*value, "arn:aws:secretsmanager:") {
+		return nil
+	}
+	s, err := secret.AWS(*value)
+	if err != nil {
+		return err
+	}
+	resolved, exists := s[key]
+	if !exists {
+		return fmt.Errorf("%w: %s: %s",
+			ErrSecretMissingKey, *value, key,
+		)
+	}
+	*value = resolved
+	return nil
+}
+
+// dbFlags are the flags of the db (shared by the commands that need it).
+func dbFlags(cfg *config.Config) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Destination: &cfg.Processor.DBBackend,
+			EnvVars:     []string{"DB_BACKEND"},
+			Name:        "db-backend",
+			Usage:       "the db backend to keep the track of alerts in (" + strings.Join(dbBackends, ", ") + ")",
+			Value:       db.BackendDynamoDB,
+		},
+
+		&cli.StringFlag{
+			Destination: &cfg.Processor.DBPath,
+			EnvVars:     []string{"DB_PATH"},
+			Name:        "db-path",
+			Usage:       "the path to the file of the " + db.BackendBolt + " db backend",
+			Value:       "prometheus-sns-lambda-slack.db",
+		},
+
+		&cli.StringFlag{
+			Destination: &cfg.Processor.DynamoDBName,
+			EnvVars:     []string{"DYNAMODB_NAME"},
+			Name:        "dynamo-db-name",
+			Usage:       "the name of Dynamo DB to keep the track of alerts",
+		},
+	}
+}
+
+func validateDB(cfg *config.Config) error {
+	switch cfg.Processor.DBBackend {
+	case db.BackendDynamoDB:
+		if cfg.Processor.DynamoDBName == "" {
+			return ErrDynamoDBMissing
+		}
+	case db.BackendBolt:
+		if cfg.Processor.DBPath == "" {
+			return ErrDBPathMissing
+		}
+	case db.BackendMemory:
+		// nothing to validate
+	default:
+		return fmt.Errorf("%w: %s",
+			ErrDBBackendInvalid, cfg.Processor.DBBackend,
+		)
+	}
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 
 	"github.com/flashbots/prometheus-sns-lambda-slack/config"
-	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
 	"github.com/urfave/cli/v2"
 	"go.uber.org/zap"
 )
@@ -26,10 +25,17 @@ const (
 func main() {
 	cfg := &config.Config{
 		Processor: config.Processor{
-			IgnoreRules: make(map[string]struct{}),
+			IgnoreRules: make(config.StringSet),
 		},
 	}
 
+	flagConfig := &cli.StringFlag{
+		Destination: &configFile,
+		EnvVars:     []string{"CONFIG_FILE"},
+		Name:        "config",
+		Usage:       "path to yaml/json configuration file (or `s3://bucket/key`, or ARN of the secret with `CONFIG` key)",
+	}
+
 	flagLogLevel := &cli.StringFlag{
 		Destination: &cfg.Log.Level,
 		EnvVars:     []string{"LOG_LEVEL"},
@@ -57,25 +63,23 @@ func main() {
 		Version: version,
 
 		Flags: []cli.Flag{
+			flagConfig,
 			flagLogLevel,
 			flagLogMode,
 		},
 
-		Before: func(ctx *cli.Context) error {
-			l, err := logutils.NewLogger(&cfg.Log)
-			if err != nil {
-				return fmt.Errorf("%w: %w",
-					ErrFailedToSetupLogging, err,
-				)
-			}
-			zap.ReplaceGlobals(l)
-			return nil
+		Before: func(clictx *cli.Context) error {
+			// the file is applied by the commands (once their flags are
+			// parsed), and so is the logging that it configures
+			return loadConfigFile()
 		},
 
 		DefaultCommand: "lambda",
 
 		Commands: []*cli.Command{
 			CommandLambda(cfg),
+			CommandServe(cfg),
+			CommandSilence(cfg),
 			Debug(cfg),
 		},
 	}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/processor"
+	"github.com/flashbots/prometheus-sns-lambda-slack/server"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrServerListenAddressMissing = errors.New("server listen address must be configured")
+	ErrServerTopicMissing         = errors.New("server topic must be configured")
+)
+
+func CommandServe(cfg *config.Config) *cli.Command {
+	base := CommandLambda(cfg)
+
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Run http server that receives alertmanager/grafana webhooks directly",
+
+		Flags: append(base.Flags, []cli.Flag{
+			&cli.StringFlag{
+				Destination: &cfg.Server.ListenAddress,
+				EnvVars:     []string{"SERVER_LISTEN_ADDRESS"},
+				Name:        "server-listen-address",
+				Usage:       "the address to listen for webhooks on",
+				Value:       "0.0.0.0:8080",
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Server.ShutdownTimeout,
+				EnvVars:     []string{"SERVER_SHUTDOWN_TIMEOUT"},
+				Name:        "server-shutdown-timeout",
+				Usage:       "the time to wait for in-flight requests to complete on shutdown",
+				Value:       30 * time.Second,
+			},
+
+			&cli.DurationFlag{
+				Destination: &cfg.Server.TickInterval,
+				EnvVars:     []string{"SERVER_TICK_INTERVAL"},
+				Name:        "server-tick-interval",
+				Usage:       "how often to run the periodic chores, like the reminders (0 disables them)",
+				Value:       time.Minute,
+			},
+
+			&cli.StringFlag{
+				Destination: &cfg.Server.Topic,
+				EnvVars:     []string{"SERVER_TOPIC"},
+				Name:        "server-topic",
+				Usage:       "the topic to track the alerts under (when it's not specified via `/alerts/{topic}` path)",
+				Value:       "webhook",
+			},
+		}...),
+
+		Before: func(clictx *cli.Context) error {
+			if err := base.Before(clictx); err != nil {
+				return err
+			}
+			if cfg.Server.ListenAddress == "" {
+				return ErrServerListenAddressMissing
+			}
+			if cfg.Server.Topic == "" {
+				return ErrServerTopicMissing
+			}
+			return nil
+		},
+
+		Action: func(_ *cli.Context) error {
+			p, err := processor.New(cfg)
+			if err != nil {
+				return err
+			}
+			return server.New(cfg, p).Run()
+		},
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+	"github.com/flashbots/prometheus-sns-lambda-slack/silence"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/google/uuid"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ErrSilenceBackendInvalid = errors.New("silences can not be managed in memory db backend")
+	ErrSilenceIDMissing      = errors.New("silence ID must be provided")
+	ErrSilenceNotFound       = errors.New("silence not found")
+)
+
+func CommandSilence(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "silence",
+		Usage: "Manage the silences",
+
+		Flags: dbFlags(cfg),
+
+		Before: func(clictx *cli.Context) error {
+			if err := applyConfigFile(clictx, cfg); err != nil {
+				return err
+			}
+			if err := setupLogging(cfg); err != nil {
+				return err
+			}
+			if err := validateDB(cfg); err != nil {
+				return err
+			}
+			if cfg.Processor.DBBackend == db.BackendMemory {
+				return ErrSilenceBackendInvalid
+			}
+			return nil
+		},
+
+		Subcommands: []*cli.Command{
+			commandSilenceAdd(cfg),
+			commandSilenceList(cfg),
+			commandSilenceExpire(cfg),
+		},
+	}
+}
+
+func commandSilenceAdd(cfg *config.Config) *cli.Command {
+	var (
+		comment   string
+		createdBy string
+		duration  time.Duration
+		endsAt    cli.Timestamp
+		matchers  cli.StringSlice
+		startsAt  cli.Timestamp
+	)
+
+	return &cli.Command{
+		Name:  "add",
+		Usage: "Add the silence",
+
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Destination: &comment,
+				Name:        "comment",
+				Usage:       "why the alerts are silenced",
+			},
+
+			&cli.StringFlag{
+				Destination: &createdBy,
+				EnvVars:     []string{"USER"},
+				Name:        "created-by",
+				Usage:       "who silences the alerts",
+			},
+
+			&cli.DurationFlag{
+				Destination: &duration,
+				Name:        "duration",
+				Usage:       "for how long the alerts are silenced (unless `--ends-at` is provided)",
+				Value:       2 * time.Hour,
+			},
+
+			&cli.TimestampFlag{
+				Destination: &endsAt,
+				Layout:      time.RFC3339,
+				Name:        "ends-at",
+				Usage:       "when the silence ends (RFC3339)",
+			},
+
+			&cli.StringSliceFlag{
+				Destination: &matchers,
+				Name:        "matcher",
+				Usage:       "prometheus-style matcher of the alerts to silence (e.g. `alertname=\"Watchdog\"`), all of which must match",
+				Required:    true,
+			},
+
+			&cli.TimestampFlag{
+				Destination: &startsAt,
+				Layout:      time.RFC3339,
+				Name:        "starts-at",
+				Usage:       "when the silence starts (RFC3339, now by default)",
+			},
+		},
+
+		Action: func(clictx *cli.Context) error {
+			now := time.Now()
+			s := &types.Silence{
+				Comment:   comment,
+				CreatedAt: now,
+				CreatedBy: createdBy,
+				ID:        uuid.New().String(),
+				Matchers:  matchers.Value(),
+				StartsAt:  now,
+			}
+			if t := startsAt.Value(); t != nil {
+				s.StartsAt = *t
+			}
+			s.EndsAt = s.StartsAt.Add(duration)
+			if t := endsAt.Value(); t != nil {
+				s.EndsAt = *t
+			}
+			if err := silence.Validate(s); err != nil {
+				return err
+			}
+
+			d, err := db.New(&cfg.Processor)
+			if err != nil {
+				return err
+			}
+			if err := d.AddSilence(context.Background(), s); err != nil {
+				return err
+			}
+
+			fmt.Println(s.ID)
+			return nil
+		},
+	}
+}
+
+func commandSilenceList(cfg *config.Config) *cli.Command {
+	var all bool
+
+	return &cli.Command{
+		Name:  "list",
+		Usage: "List the silences",
+
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Destination: &all,
+				Name:        "all",
+				Usage:       "list the expired silences as well",
+			},
+		},
+
+		Action: func(clictx *cli.Context) error {
+			d, err := db.New(&cfg.Processor)
+			if err != nil {
+				return err
+			}
+			silences, err := d.GetSilences(context.Background())
+			if err != nil {
+				return err
+			}
+			slices.SortFunc(silences, func(a, b *types.Silence) int {
+				return a.StartsAt.Compare(b.StartsAt)
+			})
+
+			now := time.Now()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSTATE\tSTARTS AT\tENDS AT\tCREATED BY\tMATCHERS\tCOMMENT")
+			for _, s := range silences {
+				state := s.State(now)
+				if state == types.SilenceStateExpired && !all {
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					s.ID,
+					state,
+					s.StartsAt.Format(time.RFC3339),
+					s.EndsAt.Format(time.RFC3339),
+					s.CreatedBy,
+					strings.Join(s.Matchers, " "),
+					s.Comment,
+				)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func commandSilenceExpire(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "expire",
+		Usage:     "Expire the silence (so that the alerts it matches are published again)",
+		ArgsUsage: "<silence-id>",
+
+		Action: func(clictx *cli.Context) error {
+			id := clictx.Args().First()
+			if id == "" {
+				return ErrSilenceIDMissing
+			}
+
+			d, err := db.New(&cfg.Processor)
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			silences, err := d.GetSilences(ctx)
+			if err != nil {
+				return err
+			}
+			idx := slices.IndexFunc(silences, func(s *types.Silence) bool {
+				return s.ID == id
+			})
+			if idx == -1 {
+				return fmt.Errorf("%w: %s",
+					ErrSilenceNotFound, id,
+				)
+			}
+
+			s := silences[idx]
+			now := time.Now()
+			if s.State(now) == types.SilenceStateExpired {
+				return nil
+			}
+			if s.StartsAt.After(now) {
+				s.StartsAt = now
+			}
+			s.EndsAt = now
+			return d.AddSilence(ctx, s)
+		},
+	}
+}
@@ -1,23 +1,192 @@
 package config
 
+import "time"
+
 type Config struct {
-	Log       Log
-	Processor Processor
-	Slack     Slack
+	Alertmanager Alertmanager `yaml:"alertmanager"`
+	Log          Log          `yaml:"log"`
+	Processor    Processor    `yaml:"processor"`
+	Server       Server       `yaml:"server"`
+	Slack        Slack        `yaml:"slack"`
+}
+
+// Alertmanager is the upstream alertmanager that the silences created from
+// slack are posted to (instead of being kept in the db).
+type Alertmanager struct {
+	// ExternalURL is where alertmanager's ui is (for the links to the
+	// silences).
+	ExternalURL string `yaml:"external_url"`
+
+	// SigV4Region enables signing the requests with AWS SigV4 (as amazon
+	// managed prometheus requires).
+	SigV4Region string `yaml:"sigv4_region"`
+
+	URL string `yaml:"url"`
 }
 
 type Log struct {
-	Level string
-	Mode  string
+	Level string `yaml:"level"`
+	Mode  string `yaml:"mode"`
 }
 
 type Processor struct {
-	DynamoDBName string
-	IgnoreRules  map[string]struct{}
+	DBBackend        string         `yaml:"db_backend"`
+	DBPath           string         `yaml:"db_path"`
+	Digest           bool           `yaml:"digest"`
+	DropRules        []*DropRule    `yaml:"drop_rules"`
+	DropRulesDryRun  bool           `yaml:"drop_rules_dry_run"`
+	DynamoDBName     string         `yaml:"dynamo_db_name"`
+	Escalation       Escalation     `yaml:"escalation"`
+	Flapping         Flapping       `yaml:"flapping"`
+	IgnoreRules      StringSet      `yaml:"ignore_rules"`
+	InhibitRules     []*InhibitRule `yaml:"inhibit_rules"`
+	LockLease        time.Duration  `yaml:"lock_lease"`
+	MessageRetention time.Duration  `yaml:"message_retention"`
+	RateLimits       RateLimits     `yaml:"rate_limits"`
+	Reminders        []*Reminder    `yaml:"reminders"`
+	SilenceNotes     bool           `yaml:"silence_notes"`
+	Thread           Thread         `yaml:"thread"`
+}
+
+// DropRule drops the alerts that match all of its matchers (prometheus-style
+// ones, e.g. `severity=~"info|none"`).
+type DropRule struct {
+	AnnotationMatchers []string `json:"annotation_matchers" yaml:"annotation_matchers"`
+	Matchers           []string `json:"matchers"            yaml:"matchers"`
+	Name               string   `json:"name"                yaml:"name"`
+}
+
+// Escalation escalates the alerts that nobody acknowledges (with the button,
+// or by reacting to the first message of the thread with one of
+// AckReactions) according to the first of the policies that matches them.
+type Escalation struct {
+	AckReactions []string            `yaml:"ack_reactions"`
+	Policies     []*EscalationPolicy `yaml:"policies"`
+}
+
+// EscalationPolicy applies to the alerts that match its matchers.  Its steps
+// are taken one after another while the alert stays unacknowledged.
+type EscalationPolicy struct {
+	Matchers []string          `json:"matchers" yaml:"matchers"`
+	Name     string            `json:"name"     yaml:"name"`
+	Steps    []*EscalationStep `json:"steps"    yaml:"steps"`
+}
+
+// EscalationStep is taken once the alert has been firing for After.  It
+// mentions the users (slack user IDs) and the user groups (slack user group
+// IDs) in the thread and, if the channel is set, posts the link to the thread
+// into that channel too.
+type EscalationStep struct {
+	After       time.Duration `json:"after"        yaml:"after"`
+	ChannelID   string        `json:"channel_id"   yaml:"channel_id"`
+	ChannelName string        `json:"channel_name" yaml:"channel_name"`
+	UserGroups  []string      `json:"user_groups"  yaml:"user_groups"`
+	Users       []string      `json:"users"        yaml:"users"`
+}
+
+// InhibitRule mutes the alerts that match the target matchers while there
+// is a firing alert that matches the source matchers and has the same values
+// of the equal labels (the same way alertmanager's inhibition works).
+type InhibitRule struct {
+	Equal          []string `json:"equal"           yaml:"equal"`
+	Fold           bool     `json:"fold"            yaml:"fold"`
+	SourceMatchers []string `json:"source_matchers" yaml:"source_matchers"`
+	TargetMatchers []string `json:"target_matchers" yaml:"target_matchers"`
+}
+
+// Flapping defines when the alert is considered flapping (its status changes
+// at least Transitions times within Window), and for how long it has to stay
+// stable to be considered settled.  The detection is disabled if Transitions
+// is zero.
+type Flapping struct {
+	StablePeriod time.Duration `yaml:"stable_period"`
+	Transitions  int           `yaml:"transitions"`
+	Window       time.Duration `yaml:"window"`
+}
+
+// RateLimits cap how fast the alerts get posted during the alert storms (per
+// channel, per alertname, and overall).  The alerts over the limits are not
+// posted, but counted and reported into their channels (at most once per
+// ReportInterval).
+type RateLimits struct {
+	Alertname      RateLimit     `yaml:"alertname"`
+	Channel        RateLimit     `yaml:"channel"`
+	Global         RateLimit     `yaml:"global"`
+	ReportInterval time.Duration `yaml:"report_interval"`
+}
+
+// RateLimit lets Burst alerts through at once, and then Burst alerts per
+// Period (it is the token bucket).  The limit is disabled if Burst is zero.
+type RateLimit struct {
+	Burst  int           `yaml:"burst"`
+	Period time.Duration `yaml:"period"`
+}
+
+// Reminder re-notifies about the alerts of the severity (or of any severity,
+// if it is empty) every RepeatInterval while they keep firing.  With Mention
+// the reminders mention the same people the first message of the thread did.
+type Reminder struct {
+	Mention        bool          `yaml:"mention"`
+	RepeatInterval time.Duration `yaml:"repeat_interval"`
+	Severity       string        `yaml:"severity"`
+}
+
+// Thread defines which alerts share the same slack thread.
+type Thread struct {
+	// IdentityLabels are the labels that identify the thread (all labels,
+	// if empty).
+	IdentityLabels []string `yaml:"identity_labels"`
+
+	// IncludeStartsAt makes the re-fired alerts start new threads (instead
+	// of joining the existing ones).
+	IncludeStartsAt bool `yaml:"include_starts_at"`
+}
+
+type Server struct {
+	ListenAddress   string        `yaml:"listen_address"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	TickInterval    time.Duration `yaml:"tick_interval"`
+	Topic           string        `yaml:"topic"`
 }
 
 type Slack struct {
-	ChannelID   string
-	ChannelName string
-	Token       string
+	ChannelID     string     `yaml:"channel_id"`
+	ChannelName   string     `yaml:"channel_name"`
+	Layout        string     `yaml:"layout"`
+	Mentions      []*Mention `yaml:"mentions"`
+	QuietResolved bool       `yaml:"quiet_resolved"`
+	Routes        []*Route   `yaml:"routes"`
+	SigningSecret string     `yaml:"signing_secret"`
+	Templates     Templates  `yaml:"templates"`
+	Token         string     `yaml:"token"`
+}
+
+// Mention notifies the users (slack user IDs), the user groups (slack user
+// group IDs), and optionally everyone (`here` or `channel`) in the first
+// message of the thread of the alert that matches its matchers.
+type Mention struct {
+	Broadcast  string   `json:"broadcast"   yaml:"broadcast"`
+	Matchers   []string `json:"matchers"    yaml:"matchers"`
+	UserGroups []string `json:"user_groups" yaml:"user_groups"`
+	Users      []string `json:"users"       yaml:"users"`
+}
+
+// Route picks the slack channel for the alerts that match its matchers (the
+// same way alertmanager's routing tree does).  The route without the channel
+// inherits it from the parent.
+type Route struct {
+	ChannelID   string   `json:"channel_id"   yaml:"channel_id"`
+	ChannelName string   `json:"channel_name" yaml:"channel_name"`
+	Continue    bool     `json:"continue"     yaml:"continue"`
+	Matchers    []string `json:"matchers"     yaml:"matchers"`
+	Routes      []*Route `json:"routes"       yaml:"routes"`
+}
+
+// Templates are go's text/template templates that render the alerts into
+// slack messages.  The ones that are left empty default to the built-in ones.
+type Templates struct {
+	Color  string `yaml:"color"`
+	Footer string `yaml:"footer"`
+	Text   string `yaml:"text"`
+	Title  string `yaml:"title"`
 }
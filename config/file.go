@@ -0,0 +1,32 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrConfigFileInvalid = errors.New("invalid configuration file")
+)
+
+// Decode strictly decodes yaml (or json) configuration on top of cfg.  The
+// fields that are absent in the data are left intact; the unknown fields are
+// reported as errors.
+func Decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(cfg); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil // empty file
+		}
+		return fmt.Errorf("%w: %w",
+			ErrConfigFileInvalid, err,
+		)
+	}
+	return nil
+}
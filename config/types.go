@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StringSet is a set of strings that is represented as a list in the
+// configuration file.
+type StringSet map[string]struct{}
+
+func (s *StringSet) UnmarshalYAML(value *yaml.Node) error {
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	if *s == nil {
+		*s = make(StringSet, len(list))
+	}
+	for _, item := range list {
+		(*s)[item] = struct{}{}
+	}
+	return nil
+}
+
+// UnmarshalJSON lets the json-encoded steps (e.g. the ones from the command
+// line) have their After as the duration string (e.g. "15m").
+func (s *EscalationStep) UnmarshalJSON(data []byte) error {
+	type plain EscalationStep
+	raw := struct {
+		*plain
+		After string `json:"after"`
+	}{plain: (*plain)(s)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	s.After = 0
+	if raw.After != "" {
+		after, err := time.ParseDuration(raw.After)
+		if err != nil {
+			return err
+		}
+		s.After = after
+	}
+	return nil
+}
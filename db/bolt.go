@@ -0,0 +1,171 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltBucket    = []byte("records")
+	boltSeparator = []byte{0}
+)
+
+type boltBackend struct {
+	db *bolt.DB
+
+	mx        sync.Mutex
+	lastPurge time.Time
+}
+
+// NewBolt returns the db that keeps everything in a single file on the local
+// disk.  It is suitable for the single-instance deployments of the server.
+func NewBolt(cfg *config.Processor) (DB, error) {
+	db, err := bolt.Open(cfg.DBPath, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &kv{
+		backend: &boltBackend{
+			db:        db,
+			lastPurge: time.Now(),
+		},
+		lockLease:        cfg.LockLease,
+		messageRetention: cfg.MessageRetention,
+	}, nil
+}
+
+func boltKey(topic, id string) []byte {
+	return bytes.Join([][]byte{[]byte(topic), []byte(id)}, boltSeparator)
+}
+
+func (b *boltBackend) get(topic, id string) (*record, error) {
+	var r *record
+	err := b.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltBucket).Get(boltKey(topic, id))
+		if raw == nil {
+			return nil
+		}
+		r = &record{}
+		return json.Unmarshal(raw, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (b *boltBackend) list(topic string) ([]*record, error) {
+	res := []*record{}
+	prefix := append([]byte(topic), boltSeparator...)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for key, raw := c.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, raw = c.Next() {
+			r := &record{}
+			if err := json.Unmarshal(raw, r); err != nil {
+				return err
+			}
+			res = append(res, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (b *boltBackend) scan(fn func(topic, id string, r *record)) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(key, raw []byte) error {
+			topic, id, found := bytes.Cut(key, boltSeparator)
+			if !found {
+				return nil
+			}
+			r := &record{}
+			if err := json.Unmarshal(raw, r); err != nil {
+				return err
+			}
+			fn(string(topic), string(id), r)
+			return nil
+		})
+	})
+}
+
+func (b *boltBackend) update(topic, id string, fn func(r *record) (*record, error)) error {
+	if err := b.purge(); err != nil {
+		return err
+	}
+
+	key := boltKey(topic, id)
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		var current *record
+		if raw := bucket.Get(key); raw != nil {
+			current = &record{}
+			if err := json.Unmarshal(raw, current); err != nil {
+				return err
+			}
+		}
+		next, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			return bucket.Delete(key)
+		}
+		raw, err := json.Marshal(next)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, raw)
+	})
+}
+
+// purge drops the expired records (at most once per purge interval).
+func (b *boltBackend) purge() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	now := time.Now()
+	if now.Sub(b.lastPurge) < purgeInterval {
+		return nil
+	}
+	b.lastPurge = now
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		expired := [][]byte{}
+		if err := bucket.ForEach(func(key, raw []byte) error {
+			var r record
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+			if r.expired(now) {
+				expired = append(expired, bytes.Clone(key))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -2,282 +2,190 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
-
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
-	"go.uber.org/zap"
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
 )
 
 const (
-	attrExpireOn       = "expire_on"
-	attrID             = "id"
-	attrSlackMessageTS = "slack_message_ts"
-	attrSlackThreadTS  = "slack_thread_ts"
-	attrSNSTopic       = "sns_topic"
-
-	lockTimeout               = time.Second
-	slackMessageExpiryTimeout = time.Minute
-	slackThreadExpiryTimeout  = 30 * 24 * time.Hour
-)
-
-type DB struct {
-	client *dynamodb.DynamoDB
-	name   string
-}
-
-func New(name string) (*DB, error) {
-	s, err := session.NewSession()
-	if err != nil {
-		return nil, err
-	}
-
-	return &DB{
-		client: dynamodb.New(s),
-		name:   name,
-	}, nil
-}
+	BackendBolt     = "bolt"
+	BackendDynamoDB = "dynamodb"
+	BackendMemory   = "memory"
 
-func (db *DB) LockSlackThread(
-	ctx context.Context,
-	topic string,
-	slackThreadID string,
-) (bool, error) {
-	l := logutils.LoggerFromContext(ctx)
+	StatusPending   = "pending"
+	StatusPublished = "published"
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
+	slackThreadExpiryTimeout = 30 * 24 * time.Hour
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(db.name),
+	// activeAlertsID is the id under which the active alerts of the topic
+	// are kept.
+	activeAlertsID = "active-alerts"
 
-		Item: map[string]*dynamodb.AttributeValue{
-			attrID:       {S: aws.String(slackThreadID)},
-			attrSNSTopic: {S: &topic},
+	// silencesTopic is the pseudo-topic the silences are kept under.
+	silencesTopic = "silences"
 
-			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
-				time.Now().Add(lockTimeout).Unix(),
-			))},
-		},
+	// silenceRetention is for how long the expired silences are kept (so
+	// that they can still be listed).
+	silenceRetention = 7 * 24 * time.Hour
 
-		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
-		ExpressionAttributeNames: map[string]*string{"#id": aws.String(attrID)},
-	}
-	output, err := db.client.PutItemWithContext(ctx, input)
+	// rateLimitsTopic is the pseudo-topic the rate limits are kept under
+	// (they are shared by all topics).
+	rateLimitsTopic = "rate-limits"
 
-	if err == nil {
-		return true, nil
-	}
-	if _, didCndChkFail := err.(*dynamodb.ConditionalCheckFailedException); didCndChkFail {
-		return false, nil
-	}
-
-	l.Error("Failed to lock the slack thread",
-		zap.Any("input", input),
-		zap.Any("output", output),
-		zap.Error(err),
-	)
-
-	return false, err
-}
-
-func (db *DB) GetSlackThreadTS(
-	ctx context.Context,
-	topic string,
-	slackThreadID string,
-) (string, error) {
-	l := logutils.LoggerFromContext(ctx)
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(db.name),
-
-		Key: map[string]*dynamodb.AttributeValue{
-			attrSNSTopic: {S: aws.String(topic)},
-			attrID:       {S: aws.String(slackThreadID)},
-		},
-	}
-
-	output, err := db.client.GetItemWithContext(ctx, input)
-	if err != nil {
-		l.Error("Failed to get slack thread timestamp",
-			zap.Any("input", input),
-			zap.Any("output", output),
-			zap.Error(err),
-		)
-		return "", err
-	}
-
-	if len(output.Item) == 0 {
-		return "", nil
-	}
+	// rateLimitAttempts is how many times the token is attempted to be
+	// taken if the concurrent invocations keep taking it at the same time.
+	rateLimitAttempts = 5
+)
 
-	ts, ok := output.Item[attrSlackThreadTS]
-	if !ok {
-		return "", nil
-	}
+var (
+	ErrRateLimitContention = errors.New("rate limit is contended")
+	ErrThreadNotFound      = errors.New("slack thread not found")
+	ErrUnknownBackend      = errors.New("unknown db backend")
+)
 
-	return *ts.S, nil
+// DB keeps the track of the alerts that were published to slack, so that
+// the follow-ups get grouped into the threads and the duplicates get dropped.
+//
+// Each message goes through the following states:
+//
+//   - absent: nobody has seen it yet (or its retention period is over);
+//   - pending: someone has locked it and is about to publish it (if that
+//     someone crashes, the lock expires at the end of the lease and the
+//     message can be locked again);
+//   - published: the message was published and its timestamp is recorded
+//     (until the end of the retention period).
+//
+// The errors are classified (see types.Kind) where the backend can tell
+// the permanent failures from the transient ones.
+type DB interface {
+	GetSlackThreadTS(ctx context.Context, topic, slackThreadID string) (string, error)
+	SetSlackThreadTS(ctx context.Context, topic, slackThreadID, slackThreadTS string) error
+
+	// GetSlackThread returns the state of the thread (or nil if there is
+	// none).
+	GetSlackThread(ctx context.Context, topic, slackThreadID string) (*types.Thread, error)
+
+	// UpdateSlackThread records that the alert was published into the thread
+	// and returns the updated state of the thread.
+	UpdateSlackThread(ctx context.Context, topic, slackThreadID string, alert *types.Alert) (*types.Thread, error)
+
+	// SetSlackThreadAction records the action taken on the thread and returns
+	// the updated state of the thread (or ErrThreadNotFound).
+	SetSlackThreadAction(ctx context.Context, topic, slackThreadID string, action *types.ThreadAction) (*types.Thread, error)
+
+	// GetSlackGroup returns the state of the digest thread of the group of
+	// alerts (or nil if there is none).
+	GetSlackGroup(ctx context.Context, topic, slackGroupID string) (*types.Group, error)
+
+	// StartSlackGroup replaces the state of the digest thread of the group
+	// (e.g. when the new thread is started).
+	StartSlackGroup(ctx context.Context, topic, slackGroupID string, group *types.Group) error
+
+	// UpdateSlackGroup records the latest state of the alerts of the group
+	// and returns the updated state of the group's digest thread.
+	UpdateSlackGroup(ctx context.Context, topic, slackGroupID string, alerts []*types.GroupAlert) (*types.Group, error)
+
+	// GetSlackThreads returns the states of all threads (across all topics,
+	// with their IDs and topics set).
+	GetSlackThreads(ctx context.Context) ([]*types.Thread, error)
+
+	// SetSlackThreadReminded records when the reminder about the alert of
+	// the thread was posted, on condition that the previous one was posted
+	// at prev (so that only one of the concurrent invocations posts it).  It
+	// returns false if the condition does not hold.  The zero times stand for
+	// no reminder.
+	SetSlackThreadReminded(ctx context.Context, topic, slackThreadID string, prev, at time.Time) (bool, error)
+
+	// SetSlackThreadEscalation records the progress of the escalation of
+	// the alert of the thread, on condition that it is still at prev (so
+	// that only one of the concurrent invocations takes the next step).  It
+	// returns false if the condition does not hold.  The nil stands for no
+	// escalation.
+	SetSlackThreadEscalation(ctx context.Context, topic, slackThreadID string, prev, next *types.Escalation) (bool, error)
+
+	// SetSlackThreadFlapping records the track of the status changes of the
+	// alert of the thread (or returns ErrThreadNotFound).
+	SetSlackThreadFlapping(ctx context.Context, topic, slackThreadID string, flapping *types.Flapping) error
+
+	// LockSlackMessage transitions the message from absent (or pending with
+	// expired lease) into pending state.  It returns false if the message is
+	// already published, or is pending and its lease is still valid.
+	LockSlackMessage(ctx context.Context, topic, slackMessageID string) (bool, error)
+
+	// UnlockSlackMessage transitions the message from pending state back to
+	// absent (so that the retries do not have to wait for the lease to end).
+	UnlockSlackMessage(ctx context.Context, topic, slackMessageID string) error
+
+	// GetSlackMessageTS returns the timestamp of the published message (or
+	// empty string if the message is not published).
+	GetSlackMessageTS(ctx context.Context, topic, slackMessageID string) (string, error)
+
+	// SetSlackMessageTS transitions the message into published state.
+	SetSlackMessageTS(ctx context.Context, topic, slackMessageID, slackMessageTS string) error
+
+	// SetActiveAlert records that the alert is firing (until it is deleted,
+	// or until its expiry).
+	SetActiveAlert(ctx context.Context, topic string, alert *types.ActiveAlert) error
+
+	// DeleteActiveAlert records that the alert with the fingerprint is not
+	// firing anymore.
+	DeleteActiveAlert(ctx context.Context, topic, fingerprint string) error
+
+	// GetActiveAlerts returns the alerts of the topic that are firing.
+	GetActiveAlerts(ctx context.Context, topic string) ([]*types.ActiveAlert, error)
+
+	// AddSilence creates the silence (or replaces the one with the same ID).
+	AddSilence(ctx context.Context, silence *types.Silence) error
+
+	// GetSilences returns all silences (including the ones that have
+	// expired recently).
+	GetSilences(ctx context.Context) ([]*types.Silence, error)
+
+	// TakeRateLimitToken takes a token from the bucket (that holds up to
+	// burst tokens and gets refilled with burst tokens per period).  It
+	// returns false if the bucket is empty.
+	TakeRateLimitToken(ctx context.Context, bucketID string, burst int, period time.Duration) (bool, error)
+
+	// ReturnRateLimitToken puts the token taken earlier back into the
+	// bucket.
+	ReturnRateLimitToken(ctx context.Context, bucketID string, burst int, period time.Duration) error
+
+	// AddRateLimitSuppressed counts the alert that the rate limits did not
+	// let into the channel.
+	AddRateLimitSuppressed(ctx context.Context, channelID string) error
+
+	// TakeRateLimitSuppressed returns (and resets) the count of the alerts
+	// suppressed on their way into the channel once the interval has passed
+	// since the first of them was counted (it returns zero until then).
+	TakeRateLimitSuppressed(ctx context.Context, channelID string, interval time.Duration) (int, error)
 }
 
-func (db DB) SetSlackThreadTS(
-	ctx context.Context,
-	topic string,
-	slackThreadID string,
-	slackThreadTS string,
-) error {
-	l := logutils.LoggerFromContext(ctx)
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(db.name),
-
-		Item: map[string]*dynamodb.AttributeValue{
-			attrID:            {S: aws.String(slackThreadID)},
-			attrSlackThreadTS: {S: aws.String(slackThreadTS)},
-			attrSNSTopic:      {S: aws.String(topic)},
-
-			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
-				time.Now().Add(slackThreadExpiryTimeout).Unix(),
-			))},
-		},
-	}
-	output, err := db.client.PutItemWithContext(ctx, input)
-	if err != nil {
-		l.Error("Failed to set slack thread timestamp",
-			zap.Any("input", input),
-			zap.Any("output", output),
-			zap.Error(err),
-		)
-		return err
-	}
-	return nil
+func silenceID(id string) string {
+	return "silence/" + id
 }
 
-func (db *DB) LockSlackMessage(
-	ctx context.Context,
-	topic string,
-	slackMessageID string,
-) (bool, error) {
-	l := logutils.LoggerFromContext(ctx)
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(db.name),
-
-		Item: map[string]*dynamodb.AttributeValue{
-			attrID:       {S: aws.String(slackMessageID)},
-			attrSNSTopic: {S: aws.String(topic)},
-
-			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
-				time.Now().Add(lockTimeout).Unix(),
-			))},
-		},
-
-		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
-		ExpressionAttributeNames: map[string]*string{"#id": aws.String(attrID)},
-	}
-	output, err := db.client.PutItemWithContext(ctx, input)
-
-	if err == nil {
-		return true, nil
-	}
-	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
-		return false, nil
-	}
-
-	l.Error("Failed to lock the slack message",
-		zap.Any("input", input),
-		zap.Any("output", output),
-		zap.Error(err),
-	)
-
-	return false, err
+func rateLimitBucketID(id string) string {
+	return "bucket/" + id
 }
 
-func (db *DB) GetSlackMessageTS(
-	ctx context.Context,
-	topic string,
-	slackMessageID string,
-) (string, error) {
-	l := logutils.LoggerFromContext(ctx)
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-
-	input := &dynamodb.GetItemInput{
-		TableName: aws.String(db.name),
-
-		Key: map[string]*dynamodb.AttributeValue{
-			attrSNSTopic: {S: aws.String(topic)},
-			attrID:       {S: aws.String(slackMessageID)},
-		},
-	}
-
-	output, err := db.client.GetItemWithContext(ctx, input)
-	if err != nil {
-		l.Error("Failed to get slack message timestamp",
-			zap.Any("input", input),
-			zap.Any("output", output),
-			zap.Error(err),
-		)
-		return "", err
-	}
-
-	if len(output.Item) == 0 {
-		return "", nil
-	}
-
-	ts, ok := output.Item[attrSlackMessageTS]
-	if !ok {
-		return "", nil
-	}
-
-	return *ts.S, nil
+func rateLimitSuppressedID(channelID string) string {
+	return "suppressed/" + channelID
 }
 
-func (db *DB) SetSlackMessageTS(
-	ctx context.Context,
-	topic string,
-	slackMessageID string,
-	slackMessageTS string,
-) error {
-	l := logutils.LoggerFromContext(ctx)
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(db.name),
-
-		Item: map[string]*dynamodb.AttributeValue{
-			attrID:             {S: aws.String(slackMessageID)},
-			attrSlackMessageTS: {S: aws.String(slackMessageTS)},
-			attrSNSTopic:       {S: aws.String(topic)},
-
-			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
-				time.Now().Add(slackThreadExpiryTimeout).Unix(),
-			))},
-		},
-	}
-	output, err := db.client.PutItemWithContext(ctx, input)
-	if err != nil {
-		l.Error("Failed to set slack thread timestamp",
-			zap.Any("input", input),
-			zap.Any("output", output),
-			zap.Error(err),
+func New(cfg *config.Processor) (DB, error) {
+	switch cfg.DBBackend {
+	case BackendDynamoDB:
+		return NewDynamoDB(cfg)
+	case BackendBolt:
+		return NewBolt(cfg)
+	case BackendMemory:
+		return NewMemory(cfg), nil
+	default:
+		return nil, fmt.Errorf("%w: %s",
+			ErrUnknownBackend, cfg.DBBackend,
 		)
-		return err
 	}
-	return nil
 }
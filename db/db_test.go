@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+)
+
+// testDB is the backend under test along with the way to make its record
+// expire (as if the time has passed).
+type testDB struct {
+	db     DB
+	expire func(t *testing.T, topic, id string)
+}
+
+func testKVs(t *testing.T) map[string]DB {
+	cfg := &config.Processor{
+		DBPath:           filepath.Join(t.TempDir(), "state.db"),
+		LockLease:        time.Minute,
+		MessageRetention: time.Hour,
+	}
+	bolt, err := NewBolt(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return map[string]DB{
+		BackendMemory: NewMemory(cfg),
+		BackendBolt:   bolt,
+	}
+}
+
+func testDBs(t *testing.T) map[string]testDB {
+	res := make(map[string]testDB)
+	for name, d := range testKVs(t) {
+		res[name] = testDB{db: d, expire: expireKV(d)}
+	}
+	dynamo := newTestDynamoDB(&config.Processor{
+		LockLease:        time.Minute,
+		MessageRetention: time.Hour,
+	})
+	res[BackendDynamoDB] = testDB{db: dynamo, expire: dynamo.client.(*fakeDynamoDB).expire}
+	return res
+}
+
+func expireKV(d DB) func(t *testing.T, topic, id string) {
+	return func(t *testing.T, topic, id string) {
+		err := d.(*kv).backend.update(topic, id, func(r *record) (*record, error) {
+			if r == nil {
+				t.Fatalf("no record to expire: %s/%s", topic, id)
+			}
+			r.ExpireOn = time.Now().Unix()
+			return r, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestMessageLifecycle(t *testing.T) {
+	const (
+		topic = "topic"
+		id    = "message/alerts/fp"
+	)
+	ctx := context.Background()
+
+	type step struct {
+		name   string
+		do     func(d DB) (any, error)
+		want   any
+		expire bool // before the step
+	}
+	lock := func(d DB) (any, error) { return d.LockSlackMessage(ctx, topic, id) }
+	get := func(d DB) (any, error) { return d.GetSlackMessageTS(ctx, topic, id) }
+	unlock := func(d DB) (any, error) { return nil, d.UnlockSlackMessage(ctx, topic, id) }
+	set := func(d DB) (any, error) { return nil, d.SetSlackMessageTS(ctx, topic, id, "1.1") }
+
+	steps := []step{
+		{name: "absent is not published", do: get, want: ""},
+		{name: "absent locks", do: lock, want: true},
+		{name: "pending does not lock", do: lock, want: false},
+		{name: "pending is not published", do: get, want: ""},
+		{name: "unlock makes it absent", do: unlock},
+		{name: "unlocked locks", do: lock, want: true},
+		{name: "pending with expired lease locks", do: lock, want: true, expire: true},
+		{name: "publish", do: set},
+		{name: "published", do: get, want: "1.1"},
+		{name: "published does not lock", do: lock, want: false},
+		{name: "published does not unlock", do: unlock},
+		{name: "still published", do: get, want: "1.1"},
+		{name: "published with expired retention is absent", do: get, want: "", expire: true},
+		{name: "published with expired retention locks", do: lock, want: true},
+	}
+
+	for name, tdb := range testDBs(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, s := range steps {
+				if s.expire {
+					tdb.expire(t, topic, id)
+				}
+				got, err := s.do(tdb.db)
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", s.name, err)
+				}
+				if got != s.want {
+					t.Fatalf("%s: want %v, got %v", s.name, s.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,1486 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"go.uber.org/zap"
+)
+
+const (
+	attrAction         = "action"
+	attrActiveAlert    = "active_alert/" // prefix, followed by the fingerprint
+	attrAlert          = "alert"
+	attrAlertStatus    = "alert_status"
+	attrBucket         = "bucket"
+	attrEscalation     = "escalation"
+	attrExpireOn       = "expire_on"
+	attrFiringCount    = "firing_count"
+	attrFlapping       = "flapping"
+	attrGroupAlert     = "group_alert/" // prefix, followed by the fingerprint
+	attrGroupLabels    = "group_labels"
+	attrID             = "id"
+	attrLastChangeAt   = "last_change_at"
+	attrRemindedAt     = "reminded_at"
+	attrResolvedCount  = "resolved_count"
+	attrSilence        = "silence"
+	attrSlackMessageTS = "slack_message_ts"
+	attrSlackThreadTS  = "slack_thread_ts"
+	attrSNSTopic       = "sns_topic"
+	attrStartedAt      = "started_at"
+	attrStatus         = "status"
+	attrStatusChanged  = "status_changed_at"
+	attrSuppressed     = "suppressed_count"
+	attrSuppressedAt   = "suppressed_at" // of the first alert counted
+)
+
+type DynamoDB struct {
+	client dynamodbiface.DynamoDBAPI
+	name   string
+
+	lockLease        time.Duration
+	messageRetention time.Duration
+}
+
+func NewDynamoDB(cfg *config.Processor) (*DynamoDB, error) {
+	s, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynamoDB{
+		client: dynamodb.New(s),
+		name:   cfg.DynamoDBName,
+
+		lockLease:        cfg.LockLease,
+		messageRetention: cfg.MessageRetention,
+	}, nil
+}
+
+func (db *DynamoDB) GetSlackThreadTS(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+	}
+
+	output, err := db.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to get slack thread timestamp",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return "", classifyDynamoDBError(err)
+	}
+
+	if len(output.Item) == 0 || numberAttr(output.Item, attrExpireOn) <= time.Now().Unix() {
+		return "", nil
+	}
+
+	ts, ok := output.Item[attrSlackThreadTS]
+	if !ok {
+		return "", nil
+	}
+
+	return *ts.S, nil
+}
+
+func (db *DynamoDB) SetSlackThreadTS(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+	slackThreadTS string,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	now := time.Now()
+	expireOn := &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%d",
+		now.Add(slackThreadExpiryTimeout).Unix(),
+	))}
+
+	// the state that is already there (e.g. the flapping track) is kept,
+	// unless it has expired (and is yet to be deleted by dynamo db)
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+
+		UpdateExpression:    aws.String("SET #slack_thread_ts = :ts, #expire_on = :expire_on"),
+		ConditionExpression: aws.String("attribute_not_exists(#id) OR #expire_on > :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on":       aws.String(attrExpireOn),
+			"#id":              aws.String(attrID),
+			"#slack_thread_ts": aws.String(attrSlackThreadTS),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":expire_on": expireOn,
+			":now":       {N: aws.String(fmt.Sprintf("%d", now.Unix()))},
+			":ts":        {S: aws.String(slackThreadTS)},
+		},
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		putInput := &dynamodb.PutItemInput{
+			TableName: aws.String(db.name),
+
+			Item: map[string]*dynamodb.AttributeValue{
+				attrExpireOn:      expireOn,
+				attrID:            {S: aws.String(slackThreadID)},
+				attrSlackThreadTS: {S: aws.String(slackThreadTS)},
+				attrSNSTopic:      {S: aws.String(topic)},
+			},
+		}
+		var putOutput *dynamodb.PutItemOutput
+		if putOutput, err = db.client.PutItemWithContext(ctx, putInput); err != nil {
+			l.Error("Failed to set slack thread timestamp",
+				zap.Any("input", putInput),
+				zap.Any("output", putOutput),
+				zap.Error(err),
+			)
+			return classifyDynamoDBError(err)
+		}
+		return nil
+	}
+	if err != nil {
+		l.Error("Failed to set slack thread timestamp",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+func (db *DynamoDB) GetSlackThread(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+) (*types.Thread, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+	}
+
+	output, err := db.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to get slack thread",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	if len(output.Item) == 0 || numberAttr(output.Item, attrExpireOn) <= time.Now().Unix() {
+		return nil, nil
+	}
+
+	return threadFromItem(output.Item)
+}
+
+func (db *DynamoDB) UpdateSlackThread(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+	alert *types.Alert,
+) (*types.Thread, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	rawAlert, err := json.Marshal(alert)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := attrFiringCount
+	if alert.Status == types.AlertStatusResolved {
+		counter = attrResolvedCount
+	}
+
+	now := time.Now()
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+
+		// the status is only updated (along with the time of its change)
+		// if it differs, see below
+		UpdateExpression: aws.String("SET " +
+			"#alert = :alert, " +
+			"#expire_on = :expire_on, " +
+			"#last_change_at = :now, " +
+			"#started_at = if_not_exists(#started_at, :now) " +
+			"ADD #counter :one",
+		),
+		ConditionExpression: aws.String("#alert_status = :status"),
+		ExpressionAttributeNames: map[string]*string{
+			"#alert":          aws.String(attrAlert),
+			"#alert_status":   aws.String(attrAlertStatus),
+			"#counter":        aws.String(counter),
+			"#expire_on":      aws.String(attrExpireOn),
+			"#last_change_at": aws.String(attrLastChangeAt),
+			"#started_at":     aws.String(attrStartedAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":alert": {S: aws.String(string(rawAlert))},
+			":expire_on": {N: aws.String(fmt.Sprintf("%d",
+				now.Add(slackThreadExpiryTimeout).Unix(),
+			))},
+			":now":    {N: aws.String(fmt.Sprintf("%d", now.Unix()))},
+			":one":    {N: aws.String("1")},
+			":status": {S: aws.String(alert.Status)},
+		},
+
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		// the status has changed (or the thread is new)
+		input.UpdateExpression = aws.String("SET " +
+			"#alert = :alert, " +
+			"#alert_status = :status, " +
+			"#expire_on = :expire_on, " +
+			"#last_change_at = :now, " +
+			"#started_at = if_not_exists(#started_at, :now), " +
+			"#status_changed_at = :now " +
+			"ADD #counter :one",
+		)
+		input.ConditionExpression = nil
+		input.ExpressionAttributeNames["#status_changed_at"] = aws.String(attrStatusChanged)
+		output, err = db.client.UpdateItemWithContext(ctx, input)
+	}
+	if err != nil {
+		l.Error("Failed to update slack thread",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	return threadFromItem(output.Attributes)
+}
+
+func (db *DynamoDB) SetSlackThreadAction(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+	action *types.ThreadAction,
+) (*types.Thread, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	rawAction, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+
+		UpdateExpression:    aws.String("SET #action = :action"),
+		ConditionExpression: aws.String("attribute_exists(#id) AND #expire_on > :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#action":    aws.String(attrAction),
+			"#expire_on": aws.String(attrExpireOn),
+			"#id":        aws.String(attrID),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":action": {S: aws.String(string(rawAction))},
+			":now":    {N: aws.String(fmt.Sprintf("%d", time.Now().Unix()))},
+		},
+
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return nil, types.Permanent(ErrThreadNotFound)
+	}
+	if err != nil {
+		l.Error("Failed to set slack thread action",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	return threadFromItem(output.Attributes)
+}
+
+// GetSlackThreads scans the table for the items of the threads (the ones
+// that have the alert).
+func (db *DynamoDB) GetSlackThreads(
+	ctx context.Context,
+) ([]*types.Thread, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	// the scan goes through the whole table (which is small, since all of
+	// its items expire), so it gets more time than the point operations
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(db.name),
+
+		FilterExpression: aws.String("attribute_exists(#alert) AND #expire_on > :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#alert":     aws.String(attrAlert),
+			"#expire_on": aws.String(attrExpireOn),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprintf("%d", time.Now().Unix()))},
+		},
+	}
+
+	res := []*types.Thread{}
+	var errUnmarshal error
+	err := db.client.ScanPagesWithContext(ctx, input, func(output *dynamodb.ScanOutput, _ bool) bool {
+		for _, item := range output.Items {
+			var thread *types.Thread
+			if thread, errUnmarshal = threadFromItem(item); errUnmarshal != nil {
+				return false
+			}
+			if id, ok := item[attrID]; ok && id.S != nil {
+				thread.ID = *id.S
+			}
+			if topic, ok := item[attrSNSTopic]; ok && topic.S != nil {
+				thread.Topic = *topic.S
+			}
+			res = append(res, thread)
+		}
+		return true
+	})
+	if err == nil {
+		err = errUnmarshal
+	}
+	if err != nil {
+		l.Error("Failed to get slack threads",
+			zap.Any("input", input),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	return res, nil
+}
+
+func (db *DynamoDB) SetSlackThreadReminded(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+	prev time.Time,
+	at time.Time,
+) (bool, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+
+		UpdateExpression:    aws.String("SET #reminded_at = :at"),
+		ConditionExpression: aws.String("attribute_exists(#id) AND #expire_on > :now AND attribute_not_exists(#reminded_at)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on":   aws.String(attrExpireOn),
+			"#id":          aws.String(attrID),
+			"#reminded_at": aws.String(attrRemindedAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":at":  {N: aws.String(fmt.Sprintf("%d", at.Unix()))},
+			":now": {N: aws.String(fmt.Sprintf("%d", time.Now().Unix()))},
+		},
+	}
+	if !prev.IsZero() {
+		input.ConditionExpression = aws.String("attribute_exists(#id) AND #expire_on > :now AND #reminded_at = :prev")
+		input.ExpressionAttributeValues[":prev"] = &dynamodb.AttributeValue{
+			N: aws.String(fmt.Sprintf("%d", prev.Unix())),
+		}
+	}
+	if at.IsZero() {
+		input.UpdateExpression = aws.String("REMOVE #reminded_at")
+		delete(input.ExpressionAttributeValues, ":at")
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+
+	if err == nil {
+		return true, nil
+	}
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return false, nil // someone else has reminded already
+	}
+
+	l.Error("Failed to set slack thread reminded",
+		zap.Any("input", input),
+		zap.Any("output", output),
+		zap.Error(err),
+	)
+	return false, classifyDynamoDBError(err)
+}
+
+func (db *DynamoDB) SetSlackThreadEscalation(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+	prev *types.Escalation,
+	next *types.Escalation,
+) (bool, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+
+		UpdateExpression:    aws.String("REMOVE #escalation"),
+		ConditionExpression: aws.String("attribute_exists(#id) AND #expire_on > :now AND attribute_not_exists(#escalation)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#escalation": aws.String(attrEscalation),
+			"#expire_on":  aws.String(attrExpireOn),
+			"#id":         aws.String(attrID),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprintf("%d", time.Now().Unix()))},
+		},
+	}
+	if prev != nil {
+		rawPrev, err := json.Marshal(prev)
+		if err != nil {
+			return false, err
+		}
+		input.ConditionExpression = aws.String("attribute_exists(#id) AND #expire_on > :now AND #escalation = :prev")
+		input.ExpressionAttributeValues[":prev"] = &dynamodb.AttributeValue{
+			S: aws.String(string(rawPrev)),
+		}
+	}
+	if next != nil {
+		rawNext, err := json.Marshal(next)
+		if err != nil {
+			return false, err
+		}
+		input.UpdateExpression = aws.String("SET #escalation = :next")
+		input.ExpressionAttributeValues[":next"] = &dynamodb.AttributeValue{
+			S: aws.String(string(rawNext)),
+		}
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+
+	if err == nil {
+		return true, nil
+	}
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return false, nil // someone else has escalated already
+	}
+
+	l.Error("Failed to set slack thread escalation",
+		zap.Any("input", input),
+		zap.Any("output", output),
+		zap.Error(err),
+	)
+	return false, classifyDynamoDBError(err)
+}
+
+func (db *DynamoDB) SetSlackThreadFlapping(
+	ctx context.Context,
+	topic string,
+	slackThreadID string,
+	flapping *types.Flapping,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	rawFlapping, err := json.Marshal(flapping)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackThreadID)},
+		},
+
+		UpdateExpression:    aws.String("SET #flapping = :flapping"),
+		ConditionExpression: aws.String("attribute_exists(#id) AND #expire_on > :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on": aws.String(attrExpireOn),
+			"#flapping":  aws.String(attrFlapping),
+			"#id":        aws.String(attrID),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":flapping": {S: aws.String(string(rawFlapping))},
+			":now":      {N: aws.String(fmt.Sprintf("%d", time.Now().Unix()))},
+		},
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return types.Permanent(ErrThreadNotFound)
+	}
+	if err != nil {
+		l.Error("Failed to set slack thread flapping",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+func threadFromItem(item map[string]*dynamodb.AttributeValue) (*types.Thread, error) {
+	thread := &types.Thread{}
+	if ts, ok := item[attrSlackThreadTS]; ok && ts.S != nil {
+		thread.TS = *ts.S
+	}
+	if rawAlert, ok := item[attrAlert]; ok && rawAlert.S != nil {
+		thread.Alert = &types.Alert{}
+		if err := json.Unmarshal([]byte(*rawAlert.S), thread.Alert); err != nil {
+			return nil, err
+		}
+	}
+	if rawAction, ok := item[attrAction]; ok && rawAction.S != nil {
+		thread.Action = &types.ThreadAction{}
+		if err := json.Unmarshal([]byte(*rawAction.S), thread.Action); err != nil {
+			return nil, err
+		}
+	}
+	if rawEscalation, ok := item[attrEscalation]; ok && rawEscalation.S != nil {
+		thread.Escalation = &types.Escalation{}
+		if err := json.Unmarshal([]byte(*rawEscalation.S), thread.Escalation); err != nil {
+			return nil, err
+		}
+	}
+	if rawFlapping, ok := item[attrFlapping]; ok && rawFlapping.S != nil {
+		thread.Flapping = &types.Flapping{}
+		if err := json.Unmarshal([]byte(*rawFlapping.S), thread.Flapping); err != nil {
+			return nil, err
+		}
+	}
+	thread.FiringCount = int(numberAttr(item, attrFiringCount))
+	thread.ResolvedCount = int(numberAttr(item, attrResolvedCount))
+	thread.LastChangeAt = time.Unix(numberAttr(item, attrLastChangeAt), 0)
+	if startedAt := numberAttr(item, attrStartedAt); startedAt != 0 {
+		thread.StartedAt = time.Unix(startedAt, 0)
+	}
+	thread.StatusChangedAt = thread.LastChangeAt // the threads from before it was tracked
+	if statusChangedAt := numberAttr(item, attrStatusChanged); statusChangedAt != 0 {
+		thread.StatusChangedAt = time.Unix(statusChangedAt, 0)
+	}
+	if remindedAt := numberAttr(item, attrRemindedAt); remindedAt != 0 {
+		thread.RemindedAt = time.Unix(remindedAt, 0)
+	}
+	return thread, nil
+}
+
+func (db *DynamoDB) GetSlackGroup(
+	ctx context.Context,
+	topic string,
+	slackGroupID string,
+) (*types.Group, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackGroupID)},
+		},
+	}
+
+	output, err := db.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to get slack group",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	if len(output.Item) == 0 || numberAttr(output.Item, attrExpireOn) <= time.Now().Unix() {
+		return nil, nil
+	}
+
+	return groupFromItem(output.Item)
+}
+
+// StartSlackGroup replaces the whole item of the group (dropping the alerts
+// of its previous thread, if any).
+func (db *DynamoDB) StartSlackGroup(
+	ctx context.Context,
+	topic string,
+	slackGroupID string,
+	group *types.Group,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	rawLabels, err := json.Marshal(group.Labels)
+	if err != nil {
+		return err
+	}
+
+	item := map[string]*dynamodb.AttributeValue{
+		attrGroupLabels:   {S: aws.String(string(rawLabels))},
+		attrID:            {S: aws.String(slackGroupID)},
+		attrLastChangeAt:  {N: aws.String(fmt.Sprintf("%d", group.LastChangeAt.Unix()))},
+		attrSlackThreadTS: {S: aws.String(group.TS)},
+		attrSNSTopic:      {S: aws.String(topic)},
+		attrStartedAt:     {N: aws.String(fmt.Sprintf("%d", group.StartedAt.Unix()))},
+
+		attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
+			time.Now().Add(slackThreadExpiryTimeout).Unix(),
+		))},
+	}
+	for fp, a := range group.Alerts {
+		rawAlert, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		item[attrGroupAlert+fp] = &dynamodb.AttributeValue{S: aws.String(string(rawAlert))}
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(db.name),
+		Item:      item,
+	}
+	output, err := db.client.PutItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to start slack group",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+// UpdateSlackGroup keeps each alert of the group in its own attribute (so
+// that the concurrent updates of different alerts do not clash).
+func (db *DynamoDB) UpdateSlackGroup(
+	ctx context.Context,
+	topic string,
+	slackGroupID string,
+	alerts []*types.GroupAlert,
+) (*types.Group, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	now := time.Now()
+	names := map[string]*string{
+		"#expire_on":      aws.String(attrExpireOn),
+		"#last_change_at": aws.String(attrLastChangeAt),
+		"#started_at":     aws.String(attrStartedAt),
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":expire_on": {N: aws.String(fmt.Sprintf("%d",
+			now.Add(slackThreadExpiryTimeout).Unix(),
+		))},
+		":now": {N: aws.String(fmt.Sprintf("%d", now.Unix()))},
+	}
+	update := "SET " +
+		"#expire_on = :expire_on, " +
+		"#last_change_at = :now, " +
+		"#started_at = if_not_exists(#started_at, :now)"
+	for i, a := range alerts {
+		rawAlert, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		names[fmt.Sprintf("#alert%d", i)] = aws.String(attrGroupAlert + a.Fingerprint)
+		values[fmt.Sprintf(":alert%d", i)] = &dynamodb.AttributeValue{S: aws.String(string(rawAlert))}
+		update += fmt.Sprintf(", #alert%d = :alert%d", i, i)
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackGroupID)},
+		},
+
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+
+		ReturnValues: aws.String(dynamodb.ReturnValueAllNew),
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to update slack group",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	return groupFromItem(output.Attributes)
+}
+
+func groupFromItem(item map[string]*dynamodb.AttributeValue) (*types.Group, error) {
+	group := &types.Group{
+		Alerts: make(map[string]*types.GroupAlert),
+	}
+	if ts, ok := item[attrSlackThreadTS]; ok && ts.S != nil {
+		group.TS = *ts.S
+	}
+	if rawLabels, ok := item[attrGroupLabels]; ok && rawLabels.S != nil {
+		if err := json.Unmarshal([]byte(*rawLabels.S), &group.Labels); err != nil {
+			return nil, err
+		}
+	}
+	for name, attr := range item {
+		if !strings.HasPrefix(name, attrGroupAlert) || attr.S == nil {
+			continue
+		}
+		a := &types.GroupAlert{}
+		if err := json.Unmarshal([]byte(*attr.S), a); err != nil {
+			return nil, err
+		}
+		group.Alerts[a.Fingerprint] = a
+	}
+	group.LastChangeAt = time.Unix(numberAttr(item, attrLastChangeAt), 0)
+	group.StartedAt = time.Unix(numberAttr(item, attrStartedAt), 0)
+	return group, nil
+}
+
+// SetActiveAlert keeps the alert in its own attribute of the topic's item
+// (so that the concurrent updates of different alerts do not clash).
+func (db *DynamoDB) SetActiveAlert(
+	ctx context.Context,
+	topic string,
+	alert *types.ActiveAlert,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	rawAlert, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(activeAlertsID)},
+		},
+
+		UpdateExpression: aws.String("SET #alert = :alert, #expire_on = :expire_on"),
+		ExpressionAttributeNames: map[string]*string{
+			"#alert":     aws.String(attrActiveAlert + alert.Fingerprint),
+			"#expire_on": aws.String(attrExpireOn),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":alert": {S: aws.String(string(rawAlert))},
+			":expire_on": {N: aws.String(fmt.Sprintf("%d",
+				time.Now().Add(slackThreadExpiryTimeout).Unix(),
+			))},
+		},
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to set active alert",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+func (db *DynamoDB) DeleteActiveAlert(
+	ctx context.Context,
+	topic string,
+	fingerprint string,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(activeAlertsID)},
+		},
+
+		UpdateExpression: aws.String("REMOVE #alert"),
+		ExpressionAttributeNames: map[string]*string{
+			"#alert": aws.String(attrActiveAlert + fingerprint),
+		},
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to delete active alert",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+func (db *DynamoDB) GetActiveAlerts(
+	ctx context.Context,
+	topic string,
+) ([]*types.ActiveAlert, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(activeAlertsID)},
+		},
+	}
+
+	output, err := db.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to get active alerts",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	res := []*types.ActiveAlert{}
+	now := time.Now()
+	for name, attr := range output.Item {
+		if !strings.HasPrefix(name, attrActiveAlert) || attr.S == nil {
+			continue
+		}
+		a := &types.ActiveAlert{}
+		if err := json.Unmarshal([]byte(*attr.S), a); err != nil {
+			return nil, err
+		}
+		if a.ExpireOn.After(now) {
+			res = append(res, a)
+		}
+	}
+
+	return res, nil
+}
+
+func (db *DynamoDB) AddSilence(
+	ctx context.Context,
+	silence *types.Silence,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	rawSilence, err := json.Marshal(silence)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(db.name),
+
+		Item: map[string]*dynamodb.AttributeValue{
+			attrID:       {S: aws.String(silenceID(silence.ID))},
+			attrSilence:  {S: aws.String(string(rawSilence))},
+			attrSNSTopic: {S: aws.String(silencesTopic)},
+
+			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
+				silence.EndsAt.Add(silenceRetention).Unix(),
+			))},
+		},
+	}
+	output, err := db.client.PutItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to add silence",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+func (db *DynamoDB) GetSilences(
+	ctx context.Context,
+) ([]*types.Silence, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.QueryInput{
+		TableName: aws.String(db.name),
+
+		KeyConditionExpression: aws.String("#sns_topic = :topic"),
+		FilterExpression:       aws.String("#expire_on > :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on": aws.String(attrExpireOn),
+			"#sns_topic": aws.String(attrSNSTopic),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now":   {N: aws.String(fmt.Sprintf("%d", time.Now().Unix()))},
+			":topic": {S: aws.String(silencesTopic)},
+		},
+	}
+
+	res := []*types.Silence{}
+	var errUnmarshal error
+	err := db.client.QueryPagesWithContext(ctx, input, func(output *dynamodb.QueryOutput, _ bool) bool {
+		for _, item := range output.Items {
+			rawSilence, ok := item[attrSilence]
+			if !ok || rawSilence.S == nil {
+				continue
+			}
+			s := &types.Silence{}
+			if errUnmarshal = json.Unmarshal([]byte(*rawSilence.S), s); errUnmarshal != nil {
+				return false
+			}
+			res = append(res, s)
+		}
+		return true
+	})
+	if err == nil {
+		err = errUnmarshal
+	}
+	if err != nil {
+		l.Error("Failed to get silences",
+			zap.Any("input", input),
+			zap.Error(err),
+		)
+		return nil, classifyDynamoDBError(err)
+	}
+
+	return res, nil
+}
+
+// TakeRateLimitToken reads the bucket and then writes it back on condition
+// that nobody has changed it in between (and starts over if somebody has).
+func (db *DynamoDB) TakeRateLimitToken(
+	ctx context.Context,
+	bucketID string,
+	burst int,
+	period time.Duration,
+) (bool, error) {
+	return db.updateRateLimitBucket(ctx, bucketID, period, func(b *types.TokenBucket, now time.Time) bool {
+		return b.Take(burst, period, now)
+	})
+}
+
+func (db *DynamoDB) ReturnRateLimitToken(
+	ctx context.Context,
+	bucketID string,
+	burst int,
+	period time.Duration,
+) error {
+	_, err := db.updateRateLimitBucket(ctx, bucketID, period, func(b *types.TokenBucket, now time.Time) bool {
+		b.Return(burst, period, now)
+		return true
+	})
+	return err
+}
+
+// updateRateLimitBucket applies fn to the bucket and writes it back (unless
+// fn returns false), retrying if the bucket was updated concurrently.
+func (db *DynamoDB) updateRateLimitBucket(
+	ctx context.Context,
+	bucketID string,
+	period time.Duration,
+	fn func(b *types.TokenBucket, now time.Time) bool,
+) (bool, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	for attempt := 1; attempt <= rateLimitAttempts; attempt++ {
+		updated, err := db.tryUpdateRateLimitBucket(ctx, bucketID, period, fn)
+		if !errors.Is(err, errConditionFailed) {
+			return updated, err
+		}
+	}
+
+	l.Warn("Failed to update rate limit bucket, out of attempts",
+		zap.String("rate_limit_bucket", bucketID),
+	)
+	return false, types.Transient(fmt.Errorf("%w: %s",
+		ErrRateLimitContention, bucketID,
+	))
+}
+
+func (db *DynamoDB) tryUpdateRateLimitBucket(
+	ctx context.Context,
+	bucketID string,
+	period time.Duration,
+	fn func(b *types.TokenBucket, now time.Time) bool,
+) (bool, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	key := map[string]*dynamodb.AttributeValue{
+		attrSNSTopic: {S: aws.String(rateLimitsTopic)},
+		attrID:       {S: aws.String(rateLimitBucketID(bucketID))},
+	}
+
+	getInput := &dynamodb.GetItemInput{
+		TableName: aws.String(db.name),
+
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	}
+	getOutput, err := db.client.GetItemWithContext(ctx, getInput)
+	if err != nil {
+		l.Error("Failed to get rate limit bucket",
+			zap.Any("input", getInput),
+			zap.Any("output", getOutput),
+			zap.Error(err),
+		)
+		return false, classifyDynamoDBError(err)
+	}
+
+	now := time.Now()
+	bucket := types.TokenBucket{}
+	prev, exists := getOutput.Item[attrBucket]
+	if exists && prev.S != nil && numberAttr(getOutput.Item, attrExpireOn) > now.Unix() {
+		if err := json.Unmarshal([]byte(*prev.S), &bucket); err != nil {
+			return false, err
+		}
+	}
+	if !fn(&bucket, now) {
+		// nothing to write back, the refill is computed from the state as-is
+		return false, nil
+	}
+
+	rawBucket, err := json.Marshal(bucket)
+	if err != nil {
+		return false, err
+	}
+
+	putInput := &dynamodb.PutItemInput{
+		TableName: aws.String(db.name),
+
+		Item: map[string]*dynamodb.AttributeValue{
+			attrBucket:   {S: aws.String(string(rawBucket))},
+			attrID:       {S: aws.String(rateLimitBucketID(bucketID))},
+			attrSNSTopic: {S: aws.String(rateLimitsTopic)},
+
+			// it's full by then anyway
+			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
+				now.Add(period).Unix()+1,
+			))},
+		},
+
+		ConditionExpression:      aws.String("attribute_not_exists(#bucket)"),
+		ExpressionAttributeNames: map[string]*string{"#bucket": aws.String(attrBucket)},
+	}
+	if exists && prev.S != nil {
+		putInput.ConditionExpression = aws.String("#bucket = :prev")
+		putInput.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":prev": {S: prev.S},
+		}
+	}
+	putOutput, err := db.client.PutItemWithContext(ctx, putInput)
+
+	if err == nil {
+		return true, nil
+	}
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return false, errConditionFailed
+	}
+
+	l.Error("Failed to update rate limit bucket",
+		zap.Any("input", putInput),
+		zap.Any("output", putOutput),
+		zap.Error(err),
+	)
+	return false, classifyDynamoDBError(err)
+}
+
+func (db *DynamoDB) AddRateLimitSuppressed(
+	ctx context.Context,
+	channelID string,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	now := time.Now()
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(rateLimitsTopic)},
+			attrID:       {S: aws.String(rateLimitSuppressedID(channelID))},
+		},
+
+		UpdateExpression: aws.String(
+			"ADD #suppressed :one SET #expire_on = :expire_on, #suppressed_at = if_not_exists(#suppressed_at, :now)",
+		),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on":     aws.String(attrExpireOn),
+			"#suppressed":    aws.String(attrSuppressed),
+			"#suppressed_at": aws.String(attrSuppressedAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":expire_on": {N: aws.String(fmt.Sprintf("%d",
+				now.Add(slackThreadExpiryTimeout).Unix(),
+			))},
+			":now": {N: aws.String(fmt.Sprintf("%d", now.Unix()))},
+			":one": {N: aws.String("1")},
+		},
+	}
+	output, err := db.client.UpdateItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to count suppressed alert",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
+
+func (db *DynamoDB) TakeRateLimitSuppressed(
+	ctx context.Context,
+	channelID string,
+	interval time.Duration,
+) (int, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	now := time.Now()
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(rateLimitsTopic)},
+			attrID:       {S: aws.String(rateLimitSuppressedID(channelID))},
+		},
+
+		// the next suppressed alert starts the new count
+		ConditionExpression: aws.String("#expire_on > :now AND #suppressed_at <= :due"),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on":     aws.String(attrExpireOn),
+			"#suppressed_at": aws.String(attrSuppressedAt),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":due": {N: aws.String(fmt.Sprintf("%d", now.Add(-interval).Unix()))},
+			":now": {N: aws.String(fmt.Sprintf("%d", now.Unix()))},
+		},
+
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	}
+	output, err := db.client.DeleteItemWithContext(ctx, input)
+
+	if err == nil {
+		return int(numberAttr(output.Attributes, attrSuppressed)), nil
+	}
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return 0, nil // nothing to report (yet)
+	}
+
+	l.Error("Failed to take suppressed alerts count",
+		zap.Any("input", input),
+		zap.Any("output", output),
+		zap.Error(err),
+	)
+	return 0, classifyDynamoDBError(err)
+}
+
+// permanentDynamoDBErrors are the errors that retrying would not fix.
+var permanentDynamoDBErrors = map[string]struct{}{
+	"AccessDeniedException":               {},
+	"MissingAuthenticationTokenException": {},
+	"ResourceNotFoundException":           {},
+	"UnrecognizedClientException":         {},
+	"ValidationException":                 {},
+}
+
+// classifyDynamoDBError marks the error as either permanent (e.g. the table
+// does not exist) or transient (e.g. throttling, timeouts).
+func classifyDynamoDBError(err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		if _, permanent := permanentDynamoDBErrors[awsErr.Code()]; permanent {
+			return types.Permanent(err)
+		}
+	}
+	return types.Transient(err)
+}
+
+func numberAttr(item map[string]*dynamodb.AttributeValue, name string) int64 {
+	attr, ok := item[name]
+	if !ok || attr.N == nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(*attr.N, 10, 64)
+	return n
+}
+
+func (db *DynamoDB) LockSlackMessage(
+	ctx context.Context,
+	topic string,
+	slackMessageID string,
+) (bool, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	now := time.Now()
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(db.name),
+
+		Item: map[string]*dynamodb.AttributeValue{
+			attrID:       {S: aws.String(slackMessageID)},
+			attrSNSTopic: {S: aws.String(topic)},
+			attrStatus:   {S: aws.String(StatusPending)},
+
+			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
+				now.Add(db.lockLease).Unix(),
+			))},
+		},
+
+		// dynamo db does not delete expired items right away, therefore we
+		// must check the expiry explicitly (the pending items expire at the
+		// end of the lease, the published ones at the end of the retention)
+		ConditionExpression: aws.String("attribute_not_exists(#id) OR #expire_on <= :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#expire_on": aws.String(attrExpireOn),
+			"#id":        aws.String(attrID),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(fmt.Sprintf("%d", now.Unix()))},
+		},
+	}
+	output, err := db.client.PutItemWithContext(ctx, input)
+
+	if err == nil {
+		return true, nil
+	}
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return false, nil
+	}
+
+	l.Error("Failed to lock the slack message",
+		zap.Any("input", input),
+		zap.Any("output", output),
+		zap.Error(err),
+	)
+
+	return false, classifyDynamoDBError(err)
+}
+
+func (db *DynamoDB) UnlockSlackMessage(
+	ctx context.Context,
+	topic string,
+	slackMessageID string,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.DeleteItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackMessageID)},
+		},
+
+		ConditionExpression:      aws.String("attribute_not_exists(#ts)"),
+		ExpressionAttributeNames: map[string]*string{"#ts": aws.String(attrSlackMessageTS)},
+	}
+	output, err := db.client.DeleteItemWithContext(ctx, input)
+
+	if err == nil {
+		return nil
+	}
+	if _, isCndChkFailedExc := err.(*dynamodb.ConditionalCheckFailedException); isCndChkFailedExc {
+		return nil // already published
+	}
+
+	l.Error("Failed to unlock the slack message",
+		zap.Any("input", input),
+		zap.Any("output", output),
+		zap.Error(err),
+	)
+
+	return classifyDynamoDBError(err)
+}
+
+func (db *DynamoDB) GetSlackMessageTS(
+	ctx context.Context,
+	topic string,
+	slackMessageID string,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String(db.name),
+
+		Key: map[string]*dynamodb.AttributeValue{
+			attrSNSTopic: {S: aws.String(topic)},
+			attrID:       {S: aws.String(slackMessageID)},
+		},
+	}
+
+	output, err := db.client.GetItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to get slack message timestamp",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return "", classifyDynamoDBError(err)
+	}
+
+	if len(output.Item) == 0 || numberAttr(output.Item, attrExpireOn) <= time.Now().Unix() {
+		return "", nil
+	}
+
+	ts, ok := output.Item[attrSlackMessageTS]
+	if !ok {
+		return "", nil
+	}
+
+	return *ts.S, nil
+}
+
+func (db *DynamoDB) SetSlackMessageTS(
+	ctx context.Context,
+	topic string,
+	slackMessageID string,
+	slackMessageTS string,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(db.name),
+
+		Item: map[string]*dynamodb.AttributeValue{
+			attrID:             {S: aws.String(slackMessageID)},
+			attrSlackMessageTS: {S: aws.String(slackMessageTS)},
+			attrSNSTopic:       {S: aws.String(topic)},
+			attrStatus:         {S: aws.String(StatusPublished)},
+
+			attrExpireOn: {N: aws.String(fmt.Sprintf("%d",
+				time.Now().Add(db.messageRetention).Unix(),
+			))},
+		},
+	}
+	output, err := db.client.PutItemWithContext(ctx, input)
+	if err != nil {
+		l.Error("Failed to set slack message timestamp",
+			zap.Any("input", input),
+			zap.Any("output", output),
+			zap.Error(err),
+		)
+		return classifyDynamoDBError(err)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+)
+
+// fakeDynamoDB keeps the items in memory and evaluates the condition
+// expressions (the subset of their syntax that the backend uses).  It only
+// implements the calls that the tests exercise.
+type fakeDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	mx    sync.Mutex
+	items map[[2]string]map[string]*dynamodb.AttributeValue
+}
+
+func newTestDynamoDB(cfg *config.Processor) *DynamoDB {
+	return &DynamoDB{
+		client: &fakeDynamoDB{
+			items: make(map[[2]string]map[string]*dynamodb.AttributeValue),
+		},
+		name: "test",
+
+		lockLease:        cfg.LockLease,
+		messageRetention: cfg.MessageRetention,
+	}
+}
+
+func fakeKey(key map[string]*dynamodb.AttributeValue) [2]string {
+	return [2]string{*key[attrSNSTopic].S, *key[attrID].S}
+}
+
+func (f *fakeDynamoDB) put(item map[string]*dynamodb.AttributeValue) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.items[fakeKey(item)] = item
+}
+
+func (f *fakeDynamoDB) expire(t *testing.T, topic, id string) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	item, exists := f.items[[2]string{topic, id}]
+	if !exists {
+		t.Fatalf("no item to expire: %s/%s", topic, id)
+	}
+	item[attrExpireOn] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Unix(), 10))}
+}
+
+func (f *fakeDynamoDB) GetItemWithContext(
+	_ aws.Context, input *dynamodb.GetItemInput, _ ...request.Option,
+) (*dynamodb.GetItemOutput, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	return &dynamodb.GetItemOutput{Item: f.items[fakeKey(input.Key)]}, nil
+}
+
+func (f *fakeDynamoDB) PutItemWithContext(
+	_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option,
+) (*dynamodb.PutItemOutput, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	key := fakeKey(input.Item)
+	if !f.holds(input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, f.items[key]) {
+		return nil, &dynamodb.ConditionalCheckFailedException{}
+	}
+	f.items[key] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItemWithContext(
+	_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option,
+) (*dynamodb.DeleteItemOutput, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	key := fakeKey(input.Key)
+	if !f.holds(input.ConditionExpression, input.ExpressionAttributeNames, input.ExpressionAttributeValues, f.items[key]) {
+		return nil, &dynamodb.ConditionalCheckFailedException{}
+	}
+	delete(f.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// holds evaluates the condition against the item (nil if there is none).
+func (f *fakeDynamoDB) holds(
+	condition *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+	item map[string]*dynamodb.AttributeValue,
+) bool {
+	if condition == nil {
+		return true
+	}
+	c := &fakeCondition{
+		item:   item,
+		names:  names,
+		tokens: strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(*condition)),
+		values: values,
+	}
+	res := c.or()
+	if len(c.tokens) != 0 {
+		panic(fmt.Sprintf("unexpected tokens in condition %q: %v", *condition, c.tokens))
+	}
+	return res
+}
+
+type fakeCondition struct {
+	item   map[string]*dynamodb.AttributeValue
+	names  map[string]*string
+	tokens []string
+	values map[string]*dynamodb.AttributeValue
+}
+
+func (c *fakeCondition) next() string {
+	t := c.tokens[0]
+	c.tokens = c.tokens[1:]
+	return t
+}
+
+func (c *fakeCondition) or() bool {
+	res := c.and()
+	for len(c.tokens) > 0 && c.tokens[0] == "OR" {
+		c.next()
+		res = c.and() || res
+	}
+	return res
+}
+
+func (c *fakeCondition) and() bool {
+	res := c.term()
+	for len(c.tokens) > 0 && c.tokens[0] == "AND" {
+		c.next()
+		res = c.term() && res
+	}
+	return res
+}
+
+func (c *fakeCondition) term() bool {
+	switch t := c.next(); t {
+	case "(":
+		res := c.or()
+		c.next() // )
+		return res
+
+	case "attribute_exists", "attribute_not_exists":
+		c.next() // (
+		_, exists := c.item[*c.names[c.next()]]
+		c.next() // )
+		return exists == (t == "attribute_exists")
+
+	default:
+		attr, exists := c.item[*c.names[t]]
+		op := c.next()
+		value := c.values[c.next()]
+		if !exists {
+			return false
+		}
+		if attr.S != nil {
+			return op == "=" && *attr.S == *value.S
+		}
+		a, _ := strconv.ParseInt(*attr.N, 10, 64)
+		v, _ := strconv.ParseInt(*value.N, 10, 64)
+		switch op {
+		case "=":
+			return a == v
+		case "<=":
+			return a <= v
+		case ">":
+			return a > v
+		}
+		panic("unexpected operator in condition: " + op)
+	}
+}
+
+func TestDynamoDBReadsSkipExpired(t *testing.T) {
+	const topic = "topic"
+	ctx := context.Background()
+	d := newTestDynamoDB(&config.Processor{})
+	fake := d.client.(*fakeDynamoDB)
+
+	expireOn := func(at time.Time) *dynamodb.AttributeValue {
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(at.Unix(), 10))}
+	}
+	live, expired := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+
+	for _, at := range []time.Time{live, expired} {
+		suffix := "live"
+		if at == expired {
+			suffix = "expired"
+		}
+		fake.put(map[string]*dynamodb.AttributeValue{
+			attrSNSTopic:       {S: aws.String(topic)},
+			attrID:             {S: aws.String("message/" + suffix)},
+			attrExpireOn:       expireOn(at),
+			attrSlackMessageTS: {S: aws.String("1.1")},
+			attrStatus:         {S: aws.String(StatusPublished)},
+		})
+		fake.put(map[string]*dynamodb.AttributeValue{
+			attrSNSTopic:      {S: aws.String(topic)},
+			attrID:            {S: aws.String("alert/" + suffix)},
+			attrExpireOn:      expireOn(at),
+			attrSlackThreadTS: {S: aws.String("2.2")},
+			attrLastChangeAt:  expireOn(time.Now()),
+		})
+	}
+
+	tests := []struct {
+		name string
+		read func() (string, error)
+		want string
+	}{
+		{
+			name: "live message",
+			read: func() (string, error) { return d.GetSlackMessageTS(ctx, topic, "message/live") },
+			want: "1.1",
+		},
+		{
+			name: "expired message",
+			read: func() (string, error) { return d.GetSlackMessageTS(ctx, topic, "message/expired") },
+			want: "",
+		},
+		{
+			name: "live thread timestamp",
+			read: func() (string, error) { return d.GetSlackThreadTS(ctx, topic, "alert/live") },
+			want: "2.2",
+		},
+		{
+			name: "expired thread timestamp",
+			read: func() (string, error) { return d.GetSlackThreadTS(ctx, topic, "alert/expired") },
+			want: "",
+		},
+		{
+			name: "live thread",
+			read: func() (string, error) {
+				thread, err := d.GetSlackThread(ctx, topic, "alert/live")
+				if thread == nil {
+					return "", err
+				}
+				return thread.TS, err
+			},
+			want: "2.2",
+		},
+		{
+			name: "expired thread",
+			read: func() (string, error) {
+				thread, err := d.GetSlackThread(ctx, topic, "alert/expired")
+				if thread == nil {
+					return "", err
+				}
+				return thread.TS, err
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,648 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+var (
+	errConditionFailed = errors.New("condition failed")
+)
+
+// record is what the embedded backends keep under each key.  It mirrors the
+// attributes of the items in dynamo db table.
+type record struct {
+	ExpireOn       int64  `json:"expire_on"`
+	SlackMessageTS string `json:"slack_message_ts,omitempty"`
+	SlackThreadTS  string `json:"slack_thread_ts,omitempty"`
+	Status         string `json:"status,omitempty"`
+
+	Alert           *types.Alert `json:"alert,omitempty"`
+	FiringCount     int          `json:"firing_count,omitempty"`
+	LastChangeAt    int64        `json:"last_change_at,omitempty"`
+	RemindedAt      int64        `json:"reminded_at,omitempty"`
+	ResolvedCount   int          `json:"resolved_count,omitempty"`
+	StartedAt       int64        `json:"started_at,omitempty"`
+	StatusChangedAt int64        `json:"status_changed_at,omitempty"`
+
+	Action       *types.ThreadAction           `json:"action,omitempty"`
+	ActiveAlerts map[string]*types.ActiveAlert `json:"active_alerts,omitempty"`
+	Escalation   *types.Escalation             `json:"escalation,omitempty"`
+	Flapping     *types.Flapping               `json:"flapping,omitempty"`
+	GroupAlerts  map[string]*types.GroupAlert  `json:"group_alerts,omitempty"`
+	GroupLabels  map[string]string             `json:"group_labels,omitempty"`
+	Silence      *types.Silence                `json:"silence,omitempty"`
+
+	Bucket          *types.TokenBucket `json:"bucket,omitempty"`
+	SuppressedCount int                `json:"suppressed_count,omitempty"`
+	SuppressedAt    int64              `json:"suppressed_at,omitempty"`
+}
+
+func (r *record) expired(now time.Time) bool {
+	return r.ExpireOn <= now.Unix()
+}
+
+// kvBackend is the storage primitive the embedded backends are built upon.
+type kvBackend interface {
+	// get returns the record stored under the key, or nil if there is none.
+	get(topic, id string) (*record, error)
+
+	// list returns all records stored under the topic.
+	list(topic string) ([]*record, error)
+
+	// scan calls fn for each record (along with its key).
+	scan(fn func(topic, id string, r *record)) error
+
+	// update atomically replaces the record stored under the key with the
+	// one returned by fn (or deletes it if fn returns nil).  fn receives nil
+	// if there is no record.  If fn fails, the storage is left untouched.
+	update(topic, id string, fn func(r *record) (*record, error)) error
+}
+
+// kv implements DB on top of kvBackend, emulating dynamo db's TTL expiry.
+type kv struct {
+	backend kvBackend
+
+	lockLease        time.Duration
+	messageRetention time.Duration
+}
+
+func (db *kv) live(r *record) *record {
+	if r == nil || r.expired(time.Now()) {
+		return nil
+	}
+	return r
+}
+
+func (db *kv) GetSlackThreadTS(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+) (string, error) {
+	r, err := db.backend.get(topic, slackThreadID)
+	if err != nil {
+		return "", err
+	}
+	if r = db.live(r); r == nil {
+		return "", nil
+	}
+	return r.SlackThreadTS, nil
+}
+
+func (db *kv) SetSlackThreadTS(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+	slackThreadTS string,
+) error {
+	return db.backend.update(topic, slackThreadID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			r = &record{}
+		}
+		r.ExpireOn = time.Now().Add(slackThreadExpiryTimeout).Unix()
+		r.SlackThreadTS = slackThreadTS
+		return r, nil
+	})
+}
+
+func (db *kv) GetSlackThread(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+) (*types.Thread, error) {
+	r, err := db.backend.get(topic, slackThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if r = db.live(r); r == nil {
+		return nil, nil
+	}
+	return r.thread(), nil
+}
+
+func (db *kv) UpdateSlackThread(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+	alert *types.Alert,
+) (*types.Thread, error) {
+	var thread *types.Thread
+	err := db.backend.update(topic, slackThreadID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			r = &record{}
+		}
+		now := time.Now()
+		if r.Alert == nil || r.Alert.Status != alert.Status {
+			r.StatusChangedAt = now.Unix()
+		}
+		r.Alert = alert.Clone()
+		r.ExpireOn = now.Add(slackThreadExpiryTimeout).Unix()
+		r.LastChangeAt = now.Unix()
+		if r.StartedAt == 0 {
+			r.StartedAt = now.Unix()
+		}
+		if alert.Status == types.AlertStatusResolved {
+			r.ResolvedCount++
+		} else {
+			r.FiringCount++
+		}
+		thread = r.thread()
+		return r, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return thread, nil
+}
+
+func (db *kv) SetSlackThreadAction(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+	action *types.ThreadAction,
+) (*types.Thread, error) {
+	var thread *types.Thread
+	err := db.backend.update(topic, slackThreadID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			return nil, errConditionFailed
+		}
+		a := *action
+		r.Action = &a
+		thread = r.thread()
+		return r, nil
+	})
+	if errors.Is(err, errConditionFailed) {
+		return nil, types.Permanent(ErrThreadNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return thread, nil
+}
+
+func (db *kv) GetSlackGroup(
+	_ context.Context,
+	topic string,
+	slackGroupID string,
+) (*types.Group, error) {
+	r, err := db.backend.get(topic, slackGroupID)
+	if err != nil {
+		return nil, err
+	}
+	if r = db.live(r); r == nil {
+		return nil, nil
+	}
+	return r.group(), nil
+}
+
+func (db *kv) StartSlackGroup(
+	_ context.Context,
+	topic string,
+	slackGroupID string,
+	group *types.Group,
+) error {
+	return db.backend.update(topic, slackGroupID, func(_ *record) (*record, error) {
+		alerts := make(map[string]*types.GroupAlert, len(group.Alerts))
+		for fp, a := range group.Alerts {
+			a := *a
+			alerts[fp] = &a
+		}
+		return &record{
+			ExpireOn:      time.Now().Add(slackThreadExpiryTimeout).Unix(),
+			GroupAlerts:   alerts,
+			GroupLabels:   maps.Clone(group.Labels),
+			LastChangeAt:  group.LastChangeAt.Unix(),
+			SlackThreadTS: group.TS,
+			StartedAt:     group.StartedAt.Unix(),
+		}, nil
+	})
+}
+
+func (db *kv) UpdateSlackGroup(
+	_ context.Context,
+	topic string,
+	slackGroupID string,
+	alerts []*types.GroupAlert,
+) (*types.Group, error) {
+	var group *types.Group
+	err := db.backend.update(topic, slackGroupID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			r = &record{}
+		}
+		now := time.Now()
+		// the backends may share the map with the readers, hence the copy
+		groupAlerts := maps.Clone(r.GroupAlerts)
+		if groupAlerts == nil {
+			groupAlerts = make(map[string]*types.GroupAlert, len(alerts))
+		}
+		for _, a := range alerts {
+			a := *a
+			groupAlerts[a.Fingerprint] = &a
+		}
+		r.GroupAlerts = groupAlerts
+		r.ExpireOn = now.Add(slackThreadExpiryTimeout).Unix()
+		r.LastChangeAt = now.Unix()
+		if r.StartedAt == 0 {
+			r.StartedAt = now.Unix()
+		}
+		group = r.group()
+		return r, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (db *kv) GetSlackThreads(
+	_ context.Context,
+) ([]*types.Thread, error) {
+	res := []*types.Thread{}
+	err := db.backend.scan(func(topic, id string, r *record) {
+		if r = db.live(r); r == nil || r.Alert == nil {
+			return
+		}
+		thread := r.thread()
+		thread.ID = id
+		thread.Topic = topic
+		res = append(res, thread)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (db *kv) SetSlackThreadReminded(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+	prev time.Time,
+	at time.Time,
+) (bool, error) {
+	err := db.backend.update(topic, slackThreadID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			return nil, errConditionFailed
+		}
+		if (prev.IsZero() && r.RemindedAt != 0) || (!prev.IsZero() && r.RemindedAt != prev.Unix()) {
+			return nil, errConditionFailed
+		}
+		r.RemindedAt = 0
+		if !at.IsZero() {
+			r.RemindedAt = at.Unix()
+		}
+		return r, nil
+	})
+	if errors.Is(err, errConditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *kv) SetSlackThreadEscalation(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+	prev *types.Escalation,
+	next *types.Escalation,
+) (bool, error) {
+	err := db.backend.update(topic, slackThreadID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			return nil, errConditionFailed
+		}
+		if !r.Escalation.Equal(prev) {
+			return nil, errConditionFailed
+		}
+		r.Escalation = next.Clone()
+		return r, nil
+	})
+	if errors.Is(err, errConditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *kv) SetSlackThreadFlapping(
+	_ context.Context,
+	topic string,
+	slackThreadID string,
+	flapping *types.Flapping,
+) error {
+	err := db.backend.update(topic, slackThreadID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			return nil, errConditionFailed
+		}
+		r.Flapping = flapping.Clone()
+		return r, nil
+	})
+	if errors.Is(err, errConditionFailed) {
+		return types.Permanent(ErrThreadNotFound)
+	}
+	return err
+}
+
+func (db *kv) LockSlackMessage(
+	_ context.Context,
+	topic string,
+	slackMessageID string,
+) (bool, error) {
+	err := db.backend.update(topic, slackMessageID, func(r *record) (*record, error) {
+		// the pending records expire at the end of the lease, the published
+		// ones at the end of the retention period
+		if db.live(r) != nil {
+			return nil, errConditionFailed
+		}
+		return &record{
+			ExpireOn: time.Now().Add(db.lockLease).Unix(),
+			Status:   StatusPending,
+		}, nil
+	})
+	if errors.Is(err, errConditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *kv) UnlockSlackMessage(
+	_ context.Context,
+	topic string,
+	slackMessageID string,
+) error {
+	err := db.backend.update(topic, slackMessageID, func(r *record) (*record, error) {
+		if r != nil && r.SlackMessageTS != "" {
+			return nil, errConditionFailed
+		}
+		return nil, nil
+	})
+	if errors.Is(err, errConditionFailed) {
+		return nil
+	}
+	return err
+}
+
+func (db *kv) GetSlackMessageTS(
+	_ context.Context,
+	topic string,
+	slackMessageID string,
+) (string, error) {
+	r, err := db.backend.get(topic, slackMessageID)
+	if err != nil {
+		return "", err
+	}
+	if r = db.live(r); r == nil {
+		return "", nil
+	}
+	return r.SlackMessageTS, nil
+}
+
+func (db *kv) SetSlackMessageTS(
+	_ context.Context,
+	topic string,
+	slackMessageID string,
+	slackMessageTS string,
+) error {
+	return db.backend.update(topic, slackMessageID, func(_ *record) (*record, error) {
+		return &record{
+			ExpireOn:       time.Now().Add(db.messageRetention).Unix(),
+			SlackMessageTS: slackMessageTS,
+			Status:         StatusPublished,
+		}, nil
+	})
+}
+
+func (db *kv) SetActiveAlert(
+	_ context.Context,
+	topic string,
+	alert *types.ActiveAlert,
+) error {
+	return db.backend.update(topic, activeAlertsID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			r = &record{}
+		}
+		// the backends may share the map with the readers, hence the copy
+		active := maps.Clone(r.ActiveAlerts)
+		if active == nil {
+			active = make(map[string]*types.ActiveAlert)
+		}
+		a := *alert
+		active[alert.Fingerprint] = &a
+		r.ActiveAlerts = active
+		r.ExpireOn = time.Now().Add(slackThreadExpiryTimeout).Unix()
+		return r, nil
+	})
+}
+
+func (db *kv) DeleteActiveAlert(
+	_ context.Context,
+	topic string,
+	fingerprint string,
+) error {
+	return db.backend.update(topic, activeAlertsID, func(r *record) (*record, error) {
+		if r = db.live(r); r == nil {
+			return nil, nil
+		}
+		active := maps.Clone(r.ActiveAlerts)
+		delete(active, fingerprint)
+		r.ActiveAlerts = active
+		return r, nil
+	})
+}
+
+func (db *kv) GetActiveAlerts(
+	_ context.Context,
+	topic string,
+) ([]*types.ActiveAlert, error) {
+	r, err := db.backend.get(topic, activeAlertsID)
+	if err != nil {
+		return nil, err
+	}
+	res := []*types.ActiveAlert{}
+	if r = db.live(r); r == nil {
+		return res, nil
+	}
+	now := time.Now()
+	for _, a := range r.ActiveAlerts {
+		if a.ExpireOn.After(now) {
+			a := *a
+			res = append(res, &a)
+		}
+	}
+	return res, nil
+}
+
+func (db *kv) AddSilence(
+	_ context.Context,
+	silence *types.Silence,
+) error {
+	return db.backend.update(silencesTopic, silenceID(silence.ID), func(_ *record) (*record, error) {
+		s := *silence
+		return &record{
+			ExpireOn: silence.EndsAt.Add(silenceRetention).Unix(),
+			Silence:  &s,
+		}, nil
+	})
+}
+
+func (db *kv) GetSilences(
+	_ context.Context,
+) ([]*types.Silence, error) {
+	records, err := db.backend.list(silencesTopic)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*types.Silence, 0, len(records))
+	for _, r := range records {
+		if r = db.live(r); r == nil || r.Silence == nil {
+			continue
+		}
+		s := *r.Silence
+		res = append(res, &s)
+	}
+	return res, nil
+}
+
+func (db *kv) TakeRateLimitToken(
+	_ context.Context,
+	bucketID string,
+	burst int,
+	period time.Duration,
+) (bool, error) {
+	taken := false
+	err := db.updateRateLimitBucket(bucketID, period, func(b *types.TokenBucket, now time.Time) {
+		taken = b.Take(burst, period, now)
+	})
+	if err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+func (db *kv) ReturnRateLimitToken(
+	_ context.Context,
+	bucketID string,
+	burst int,
+	period time.Duration,
+) error {
+	return db.updateRateLimitBucket(bucketID, period, func(b *types.TokenBucket, now time.Time) {
+		b.Return(burst, period, now)
+	})
+}
+
+func (db *kv) updateRateLimitBucket(
+	bucketID string,
+	period time.Duration,
+	fn func(b *types.TokenBucket, now time.Time),
+) error {
+	return db.backend.update(rateLimitsTopic, rateLimitBucketID(bucketID), func(r *record) (*record, error) {
+		bucket := types.TokenBucket{}
+		if r = db.live(r); r != nil && r.Bucket != nil {
+			bucket = *r.Bucket
+		}
+		now := time.Now()
+		fn(&bucket, now)
+		return &record{
+			Bucket:   &bucket,
+			ExpireOn: now.Add(period).Unix() + 1, // it's full by then anyway
+		}, nil
+	})
+}
+
+func (db *kv) AddRateLimitSuppressed(
+	_ context.Context,
+	channelID string,
+) error {
+	return db.backend.update(rateLimitsTopic, rateLimitSuppressedID(channelID), func(r *record) (*record, error) {
+		now := time.Now()
+		if r = db.live(r); r == nil {
+			r = &record{SuppressedAt: now.Unix()}
+		}
+		r.ExpireOn = now.Add(slackThreadExpiryTimeout).Unix()
+		r.SuppressedCount++
+		return r, nil
+	})
+}
+
+func (db *kv) TakeRateLimitSuppressed(
+	_ context.Context,
+	channelID string,
+	interval time.Duration,
+) (int, error) {
+	count := 0
+	err := db.backend.update(rateLimitsTopic, rateLimitSuppressedID(channelID), func(r *record) (*record, error) {
+		if r = db.live(r); r == nil || r.SuppressedAt > time.Now().Add(-interval).Unix() {
+			return nil, errConditionFailed
+		}
+		count = r.SuppressedCount
+		return nil, nil // the next one starts the new count
+	})
+	if errors.Is(err, errConditionFailed) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *record) thread() *types.Thread {
+	var action *types.ThreadAction
+	if r.Action != nil {
+		a := *r.Action
+		action = &a
+	}
+	var remindedAt time.Time
+	if r.RemindedAt != 0 {
+		remindedAt = time.Unix(r.RemindedAt, 0)
+	}
+	var startedAt time.Time
+	if r.StartedAt != 0 {
+		startedAt = time.Unix(r.StartedAt, 0)
+	}
+	statusChangedAt := r.StatusChangedAt
+	if statusChangedAt == 0 {
+		// the threads from before it was tracked
+		statusChangedAt = r.LastChangeAt
+	}
+	return &types.Thread{
+		TS:            r.SlackThreadTS,
+		Action:        action,
+		Escalation:    r.Escalation.Clone(),
+		Flapping:      r.Flapping.Clone(),
+		Alert:         r.Alert.Clone(),
+		FiringCount:   r.FiringCount,
+		LastChangeAt:  time.Unix(r.LastChangeAt, 0),
+		RemindedAt:    remindedAt,
+		ResolvedCount: r.ResolvedCount,
+		StartedAt:     startedAt,
+
+		StatusChangedAt: time.Unix(statusChangedAt, 0),
+	}
+}
+
+func (r *record) group() *types.Group {
+	alerts := make(map[string]*types.GroupAlert, len(r.GroupAlerts))
+	for fp, a := range r.GroupAlerts {
+		a := *a
+		alerts[fp] = &a
+	}
+	return &types.Group{
+		TS:           r.SlackThreadTS,
+		Alerts:       alerts,
+		Labels:       maps.Clone(r.GroupLabels),
+		LastChangeAt: time.Unix(r.LastChangeAt, 0),
+		StartedAt:    time.Unix(r.StartedAt, 0),
+	}
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+func TestKVThreadUpdates(t *testing.T) {
+	const (
+		topic = "topic"
+		id    = "alert/alerts/fp"
+	)
+	ctx := context.Background()
+
+	firing := &types.Alert{Status: types.AlertStatusFiring}
+	resolved := &types.Alert{Status: types.AlertStatusResolved}
+
+	tests := []struct {
+		alert         *types.Alert
+		firingCount   int
+		resolvedCount int
+		statusChanged bool
+	}{
+		{alert: firing, firingCount: 1, statusChanged: true},
+		{alert: firing, firingCount: 2, statusChanged: false},
+		{alert: resolved, firingCount: 2, resolvedCount: 1, statusChanged: true},
+		{alert: resolved, firingCount: 2, resolvedCount: 2, statusChanged: false},
+		{alert: firing, firingCount: 3, resolvedCount: 2, statusChanged: true},
+	}
+
+	for name, d := range testKVs(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := d.SetSlackThreadTS(ctx, topic, id, "1.1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var prev *types.Thread
+			for idx, tt := range tests {
+				thread, err := d.UpdateSlackThread(ctx, topic, id, tt.alert)
+				if err != nil {
+					t.Fatalf("update %d: unexpected error: %v", idx, err)
+				}
+				if thread.TS != "1.1" {
+					t.Errorf("update %d: want the thread timestamp kept, got %q", idx, thread.TS)
+				}
+				if thread.Status() != tt.alert.Status {
+					t.Errorf("update %d: want status %q, got %q", idx, tt.alert.Status, thread.Status())
+				}
+				if thread.FiringCount != tt.firingCount || thread.ResolvedCount != tt.resolvedCount {
+					t.Errorf("update %d: want counts %d/%d, got %d/%d", idx,
+						tt.firingCount, tt.resolvedCount, thread.FiringCount, thread.ResolvedCount,
+					)
+				}
+				if thread.StartedAt.IsZero() || (prev != nil && !thread.StartedAt.Equal(prev.StartedAt)) {
+					t.Errorf("update %d: want the start kept, got %v", idx, thread.StartedAt)
+				}
+				if prev != nil && tt.statusChanged != thread.StatusChangedAt.After(prev.StatusChangedAt) {
+					t.Errorf("update %d: want the status change moved %v, got %v after %v", idx,
+						tt.statusChanged, thread.StatusChangedAt, prev.StatusChangedAt,
+					)
+				}
+				// the timestamps are in seconds, so set the status change
+				// back to tell whether the next update moves it
+				rewind(t, d, topic, id)
+				prev, _ = d.GetSlackThread(ctx, topic, id)
+			}
+		})
+	}
+}
+
+// rewind moves the status change of the thread into the past.
+func rewind(t *testing.T, d DB, topic, id string) {
+	err := d.(*kv).backend.update(topic, id, func(r *record) (*record, error) {
+		r.StatusChangedAt -= 60
+		return r, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestKVThreadConditions(t *testing.T) {
+	const (
+		topic = "topic"
+		id    = "alert/alerts/fp"
+	)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	escalation := &types.Escalation{EscalatedAt: now, Level: 1, Since: now.Add(-time.Hour)}
+
+	for name, d := range testKVs(t) {
+		t.Run(name, func(t *testing.T) {
+			_, err := d.SetSlackThreadAction(ctx, topic, id, &types.ThreadAction{At: now})
+			if !errors.Is(err, ErrThreadNotFound) || types.Kind(err) != types.ErrPermanent {
+				t.Errorf("want permanent %v for the action on absent thread, got %v", ErrThreadNotFound, err)
+			}
+			if err := d.SetSlackThreadFlapping(ctx, topic, id, &types.Flapping{}); !errors.Is(err, ErrThreadNotFound) {
+				t.Errorf("want %v for the flapping of absent thread, got %v", ErrThreadNotFound, err)
+			}
+			if ok, err := d.SetSlackThreadReminded(ctx, topic, id, time.Time{}, now); ok || err != nil {
+				t.Errorf("want no reminder of absent thread, got %v, %v", ok, err)
+			}
+
+			if _, err := d.UpdateSlackThread(ctx, topic, id, &types.Alert{Status: types.AlertStatusFiring}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			steps := []struct {
+				name string
+				do   func() (bool, error)
+				want bool
+			}{
+				{
+					name: "first reminder",
+					do:   func() (bool, error) { return d.SetSlackThreadReminded(ctx, topic, id, time.Time{}, now) },
+					want: true,
+				},
+				{
+					name: "concurrent first reminder",
+					do:   func() (bool, error) { return d.SetSlackThreadReminded(ctx, topic, id, time.Time{}, now) },
+					want: false,
+				},
+				{
+					name: "next reminder",
+					do: func() (bool, error) {
+						return d.SetSlackThreadReminded(ctx, topic, id, now, now.Add(time.Hour))
+					},
+					want: true,
+				},
+				{
+					name: "reminder with stale prev",
+					do: func() (bool, error) {
+						return d.SetSlackThreadReminded(ctx, topic, id, now, now.Add(2*time.Hour))
+					},
+					want: false,
+				},
+				{
+					name: "first escalation",
+					do:   func() (bool, error) { return d.SetSlackThreadEscalation(ctx, topic, id, nil, escalation) },
+					want: true,
+				},
+				{
+					name: "concurrent first escalation",
+					do:   func() (bool, error) { return d.SetSlackThreadEscalation(ctx, topic, id, nil, escalation) },
+					want: false,
+				},
+				{
+					name: "escalation rolled back",
+					do:   func() (bool, error) { return d.SetSlackThreadEscalation(ctx, topic, id, escalation, nil) },
+					want: true,
+				},
+			}
+			for _, s := range steps {
+				got, err := s.do()
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", s.name, err)
+				}
+				if got != s.want {
+					t.Errorf("%s: want %v, got %v", s.name, s.want, got)
+				}
+			}
+
+			thread, err := d.SetSlackThreadAction(ctx, topic, id, &types.ThreadAction{
+				At:   time.Now(),
+				Type: types.ThreadActionAcknowledge,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !thread.RemindedAt.Equal(now.Add(time.Hour)) || thread.Escalation != nil || !thread.Acknowledged() {
+				t.Errorf("want the reminder, no escalation, and the acknowledgement, got %+v", thread)
+			}
+
+			expireKV(d)(t, topic, id)
+			if thread, err := d.GetSlackThread(ctx, topic, id); thread != nil || err != nil {
+				t.Errorf("want no expired thread, got %+v, %v", thread, err)
+			}
+			if threads, err := d.GetSlackThreads(ctx); len(threads) != 0 || err != nil {
+				t.Errorf("want no expired threads listed, got %d, %v", len(threads), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+)
+
+const (
+	purgeInterval = time.Minute
+)
+
+type memoryKey struct {
+	topic string
+	id    string
+}
+
+type memoryBackend struct {
+	mx        sync.Mutex
+	lastPurge time.Time
+	records   map[memoryKey]record
+}
+
+// NewMemory returns the db that keeps everything in memory.  It is only
+// suitable for the single-instance deployments (and for the local runs),
+// since the state is neither shared nor persisted.
+func NewMemory(cfg *config.Processor) DB {
+	return &kv{
+		backend: &memoryBackend{
+			lastPurge: time.Now(),
+			records:   make(map[memoryKey]record),
+		},
+		lockLease:        cfg.LockLease,
+		messageRetention: cfg.MessageRetention,
+	}
+}
+
+func (b *memoryBackend) get(topic, id string) (*record, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	r, exists := b.records[memoryKey{topic: topic, id: id}]
+	if !exists {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+func (b *memoryBackend) list(topic string) ([]*record, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	res := []*record{}
+	for key, r := range b.records {
+		if key.topic == topic {
+			r := r
+			res = append(res, &r)
+		}
+	}
+	return res, nil
+}
+
+func (b *memoryBackend) scan(fn func(topic, id string, r *record)) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for key, r := range b.records {
+		r := r
+		fn(key.topic, key.id, &r)
+	}
+	return nil
+}
+
+func (b *memoryBackend) update(topic, id string, fn func(r *record) (*record, error)) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.purge()
+
+	key := memoryKey{topic: topic, id: id}
+	var current *record
+	if r, exists := b.records[key]; exists {
+		current = &r
+	}
+	next, err := fn(current)
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		delete(b.records, key)
+		return nil
+	}
+	b.records[key] = *next
+	return nil
+}
+
+// purge drops the expired records.  Must be called under the lock.
+func (b *memoryBackend) purge() {
+	now := time.Now()
+	if now.Sub(b.lastPurge) < purgeInterval {
+		return
+	}
+	b.lastPurge = now
+	for key, r := range b.records {
+		if r.expired(now) {
+			delete(b.records, key)
+		}
+	}
+}
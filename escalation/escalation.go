@@ -0,0 +1,161 @@
+package escalation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/publisher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/router"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+var (
+	ErrPolicyInvalidMatcher = errors.New("escalation policy has invalid matcher")
+	ErrPolicyInvalidStep    = errors.New("escalation policy has invalid step")
+	ErrPolicyNoSteps        = errors.New("escalation policy must have steps")
+)
+
+// Escalator picks the escalation policy for the alerts, and tells which step
+// of it is next.
+type Escalator struct {
+	ackReactions []string
+	channels     []router.Channel
+	policies     []*policy
+}
+
+type policy struct {
+	matchers matcher.Matchers
+	name     string
+	steps    []*Step
+}
+
+// Step is the step of the escalation policy.
+type Step struct {
+	*config.EscalationStep
+
+	// Level is the count of the steps taken once this one is (starting at 1).
+	Level int
+
+	// Mentions are the users and the user groups of the step formatted the
+	// way slack expects them.
+	Mentions string
+
+	Policy string
+}
+
+func New(cfg *config.Escalation) (*Escalator, error) {
+	e := &Escalator{
+		ackReactions: cfg.AckReactions,
+	}
+	for idx, c := range cfg.Policies {
+		p := fmt.Sprintf("escalation.policies[%d]", idx)
+
+		if len(c.Steps) == 0 {
+			return nil, fmt.Errorf("%w: %s",
+				ErrPolicyNoSteps, p,
+			)
+		}
+		matchers, err := matcher.ParseAll(c.Matchers)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s.matchers: %w",
+				ErrPolicyInvalidMatcher, p, err,
+			)
+		}
+		policy := &policy{
+			matchers: matchers,
+			name:     c.Name,
+		}
+		for sidx, s := range c.Steps {
+			step, err := newStep(fmt.Sprintf("%s.steps[%d]", p, sidx), s, c.Steps[:sidx])
+			if err != nil {
+				return nil, err
+			}
+			step.Level = sidx + 1
+			step.Policy = c.Name
+			policy.steps = append(policy.steps, step)
+
+			if s.ChannelName != "" && !slices.ContainsFunc(e.channels, func(c router.Channel) bool {
+				return c.Name == s.ChannelName
+			}) {
+				e.channels = append(e.channels, router.Channel{ID: s.ChannelID, Name: s.ChannelName})
+			}
+		}
+		e.policies = append(e.policies, policy)
+	}
+	return e, nil
+}
+
+func newStep(p string, cfg *config.EscalationStep, prev []*config.EscalationStep) (*Step, error) {
+	if cfg.After <= 0 {
+		return nil, fmt.Errorf("%w: %s: after must be positive",
+			ErrPolicyInvalidStep, p,
+		)
+	}
+	if len(prev) > 0 && cfg.After <= prev[len(prev)-1].After {
+		return nil, fmt.Errorf("%w: %s: after must be longer than the one of the previous step",
+			ErrPolicyInvalidStep, p,
+		)
+	}
+	if (cfg.ChannelID == "") != (cfg.ChannelName == "") {
+		return nil, fmt.Errorf("%w: %s: channel must have both ID and name",
+			ErrPolicyInvalidStep, p,
+		)
+	}
+	step := &Step{EscalationStep: cfg}
+	if len(cfg.Users)+len(cfg.UserGroups) > 0 {
+		m, err := publisher.NewMentions([]*config.Mention{{
+			UserGroups: cfg.UserGroups,
+			Users:      cfg.Users,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w",
+				ErrPolicyInvalidStep, p, err,
+			)
+		}
+		step.Mentions = m.Text(nil)
+	} else if cfg.ChannelName == "" {
+		return nil, fmt.Errorf("%w: %s: must have users, user groups or channel",
+			ErrPolicyInvalidStep, p,
+		)
+	}
+	return step, nil
+}
+
+// Enabled tells whether there are any policies.
+func (e *Escalator) Enabled() bool {
+	return len(e.policies) > 0
+}
+
+// AckReactions are the emoji reactions to the first message of the thread
+// that acknowledge the alert.
+func (e *Escalator) AckReactions() []string {
+	return e.ackReactions
+}
+
+// Channels returns the distinct channels the steps of the policies post to.
+func (e *Escalator) Channels() []router.Channel {
+	return e.channels
+}
+
+// Next returns the step of the policy that matches the alert with the labels
+// that follows the ones taken by the escalation so far (or nil if there is
+// none).
+func (e *Escalator) Next(labels map[string]string, escalation *types.Escalation) *Step {
+	level := 0
+	if escalation != nil {
+		level = escalation.Level
+	}
+	for _, p := range e.policies {
+		if !p.matchers.Matches(labels) {
+			continue
+		}
+		if level >= len(p.steps) {
+			return nil
+		}
+		return p.steps[level]
+	}
+	return nil
+}
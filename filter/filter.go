@@ -0,0 +1,134 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+)
+
+var (
+	ErrDropRuleDuplicateName = errors.New("drop rule name must be unique")
+	ErrDropRuleEmpty         = errors.New("drop rule must have at least one matcher")
+	ErrDropRuleInvalid       = errors.New("drop rule has invalid matcher")
+)
+
+// ignoreRulesName is the name of the rule that drops the alerts listed in
+// legacy `ignore-rules`.
+const ignoreRulesName = "ignore-rules"
+
+// Filter drops the alerts that match any of its rules.  A rule matches when
+// all of its label and annotation matchers do.
+type Filter struct {
+	dryRun bool
+	rules  []*Rule
+}
+
+// Rule is the drop rule along with the count of the alerts it has dropped.
+type Rule struct {
+	Name string
+
+	annotations matcher.Matchers
+	labels      matcher.Matchers
+	drops       atomic.Uint64
+}
+
+func New(cfg *config.Processor) (*Filter, error) {
+	f := &Filter{
+		dryRun: cfg.DropRulesDryRun,
+	}
+
+	names := make(map[string]struct{}, len(cfg.DropRules))
+	for idx, c := range cfg.DropRules {
+		p := fmt.Sprintf("drop_rules[%d]", idx)
+
+		r := &Rule{Name: c.Name}
+		if r.Name == "" {
+			r.Name = p
+		}
+		if _, duplicate := names[r.Name]; duplicate {
+			return nil, fmt.Errorf("%w: %s: %s",
+				ErrDropRuleDuplicateName, p, r.Name,
+			)
+		}
+		names[r.Name] = struct{}{}
+
+		if len(c.Matchers)+len(c.AnnotationMatchers) == 0 {
+			return nil, fmt.Errorf("%w: %s",
+				ErrDropRuleEmpty, p,
+			)
+		}
+		var err error
+		if r.labels, err = matcher.ParseAll(c.Matchers); err != nil {
+			return nil, fmt.Errorf("%w: %s.matchers: %w",
+				ErrDropRuleInvalid, p, err,
+			)
+		}
+		if r.annotations, err = matcher.ParseAll(c.AnnotationMatchers); err != nil {
+			return nil, fmt.Errorf("%w: %s.annotation_matchers: %w",
+				ErrDropRuleInvalid, p, err,
+			)
+		}
+		f.rules = append(f.rules, r)
+	}
+
+	// the legacy ignore-rules are the exact matches of alertname
+	if len(cfg.IgnoreRules) > 0 {
+		alertnames := make([]string, 0, len(cfg.IgnoreRules))
+		for alertname := range cfg.IgnoreRules {
+			alertnames = append(alertnames, regexp.QuoteMeta(alertname))
+		}
+		sort.Strings(alertnames)
+
+		m, err := matcher.New("alertname", matcher.TypeRegexp, strings.Join(alertnames, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w",
+				ErrDropRuleInvalid, ignoreRulesName, err,
+			)
+		}
+		f.rules = append(f.rules, &Rule{
+			Name:   ignoreRulesName,
+			labels: matcher.Matchers{m},
+		})
+	}
+
+	return f, nil
+}
+
+// DryRun tells whether the matching alerts should only be reported (and not
+// actually dropped).
+func (f *Filter) DryRun() bool {
+	return f.dryRun
+}
+
+// Match returns the first rule that matches the alert with the labels and
+// the annotations (or nil if none does), and counts the drop against it.
+func (f *Filter) Match(labels, annotations map[string]string) *Rule {
+	for _, r := range f.rules {
+		if r.labels.Matches(labels) && r.annotations.Matches(annotations) {
+			r.drops.Add(1)
+			return r
+		}
+	}
+	return nil
+}
+
+// Drops returns how many alerts each of the rules has dropped (or would
+// have dropped, in dry-run mode) since the start.
+func (f *Filter) Drops() map[string]uint64 {
+	res := make(map[string]uint64, len(f.rules))
+	for _, r := range f.rules {
+		res[r.Name] = r.drops.Load()
+	}
+	return res
+}
+
+// Drops returns how many alerts the rule has dropped since the start.
+func (r *Rule) Drops() uint64 {
+	return r.drops.Load()
+}
@@ -0,0 +1,131 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+)
+
+func TestMatch(t *testing.T) {
+	f, err := New(&config.Processor{
+		DropRules: []*config.DropRule{
+			{
+				Name:     "info",
+				Matchers: []string{`severity=~"info|none"`},
+			},
+			{
+				Name:               "runbook-less",
+				Matchers:           []string{`team="web"`},
+				AnnotationMatchers: []string{`runbook_url=""`},
+			},
+			{
+				// unnamed rules are named after their position
+				Matchers: []string{`env="dev"`},
+			},
+		},
+		IgnoreRules: config.StringSet{
+			"Watchdog":   {},
+			"Info.Inhib": {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        string
+	}{
+		{name: "no match", labels: map[string]string{"severity": "critical"}, want: ""},
+		{name: "label matchers", labels: map[string]string{"severity": "info"}, want: "info"},
+		{
+			name:        "label and annotation matchers",
+			labels:      map[string]string{"team": "web"},
+			annotations: map[string]string{"summary": "slow"},
+			want:        "runbook-less",
+		},
+		{
+			name:        "annotation matchers do not match",
+			labels:      map[string]string{"team": "web"},
+			annotations: map[string]string{"runbook_url": "http://runbooks/slow"},
+			want:        "",
+		},
+		{name: "first rule wins", labels: map[string]string{"severity": "none", "env": "dev"}, want: "info"},
+		{name: "unnamed rule", labels: map[string]string{"env": "dev"}, want: "drop_rules[2]"},
+		{name: "ignore rules", labels: map[string]string{"alertname": "Watchdog"}, want: ignoreRulesName},
+		{name: "ignore rules are literal", labels: map[string]string{"alertname": "InfoXInhib"}, want: ""},
+		{name: "ignore rules are anchored", labels: map[string]string{"alertname": "WatchdogDown"}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if r := f.Match(tt.labels, tt.annotations); r != nil {
+				got = r.Name
+			}
+			if got != tt.want {
+				t.Errorf("want rule %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	want := map[string]uint64{
+		"info":          2,
+		"runbook-less":  1,
+		"drop_rules[2]": 1,
+		ignoreRulesName: 1,
+	}
+	drops := f.Drops()
+	if len(drops) != len(want) {
+		t.Fatalf("want drops %v, got %v", want, drops)
+	}
+	for name, count := range want {
+		if drops[name] != count {
+			t.Errorf("want %d drops of %q, got %d", count, name, drops[name])
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*config.DropRule
+		errIs error
+	}{
+		{
+			name:  "empty",
+			rules: []*config.DropRule{{Name: "empty"}},
+			errIs: ErrDropRuleEmpty,
+		},
+		{
+			name:  "invalid matcher",
+			rules: []*config.DropRule{{Matchers: []string{`severity`}}},
+			errIs: ErrDropRuleInvalid,
+		},
+		{
+			name:  "invalid annotation matcher",
+			rules: []*config.DropRule{{AnnotationMatchers: []string{`summary=~"("`}}},
+			errIs: ErrDropRuleInvalid,
+		},
+		{
+			name: "duplicate name",
+			rules: []*config.DropRule{
+				{Name: "noise", Matchers: []string{`severity="info"`}},
+				{Name: "noise", Matchers: []string{`severity="none"`}},
+			},
+			errIs: ErrDropRuleDuplicateName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&config.Processor{DropRules: tt.rules})
+			if !errors.Is(err, tt.errIs) {
+				t.Errorf("want error %v, got %v", tt.errIs, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package inhibit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+var (
+	ErrInhibitRuleInvalidMatcher = errors.New("inhibit rule has invalid matcher")
+	ErrInhibitRuleNoMatchers     = errors.New("inhibit rule must have both source and target matchers")
+)
+
+// Inhibitor mutes the alerts (the targets) while the other alerts (the
+// sources) are firing.
+type Inhibitor struct {
+	rules []*rule
+}
+
+type rule struct {
+	equal  []string
+	fold   bool
+	source matcher.Matchers
+	target matcher.Matchers
+}
+
+// Inhibition tells which active alert inhibits the target, and whether the
+// target should be folded into its thread (instead of being dropped).
+type Inhibition struct {
+	Fold   bool
+	Source *types.ActiveAlert
+}
+
+func New(cfg *config.Processor) (*Inhibitor, error) {
+	i := &Inhibitor{}
+	for idx, c := range cfg.InhibitRules {
+		p := fmt.Sprintf("inhibit_rules[%d]", idx)
+
+		if len(c.SourceMatchers) == 0 || len(c.TargetMatchers) == 0 {
+			return nil, fmt.Errorf("%w: %s",
+				ErrInhibitRuleNoMatchers, p,
+			)
+		}
+		source, err := matcher.ParseAll(c.SourceMatchers)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s.source_matchers: %w",
+				ErrInhibitRuleInvalidMatcher, p, err,
+			)
+		}
+		target, err := matcher.ParseAll(c.TargetMatchers)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s.target_matchers: %w",
+				ErrInhibitRuleInvalidMatcher, p, err,
+			)
+		}
+		i.rules = append(i.rules, &rule{
+			equal:  c.Equal,
+			fold:   c.Fold,
+			source: source,
+			target: target,
+		})
+	}
+	return i, nil
+}
+
+// IsSource tells whether the alert with the labels can inhibit the others
+// (that is, whether it must be tracked while it's firing).
+func (i *Inhibitor) IsSource(labels map[string]string) bool {
+	for _, r := range i.rules {
+		if r.source.Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTarget tells whether the alert with the labels can be inhibited.
+func (i *Inhibitor) IsTarget(labels map[string]string) bool {
+	for _, r := range i.rules {
+		if r.target.Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the inhibition of the alert with the labels and the
+// fingerprint by one of the active alerts (or nil if it's not inhibited).
+func (i *Inhibitor) Match(
+	labels map[string]string,
+	fingerprint string,
+	active []*types.ActiveAlert,
+) *Inhibition {
+	for _, r := range i.rules {
+		if !r.target.Matches(labels) {
+			continue
+		}
+		for _, a := range active {
+			if a.Fingerprint == fingerprint {
+				continue // the alert does not inhibit itself
+			}
+			if !r.source.Matches(a.Labels) || !r.equals(labels, a.Labels) {
+				continue
+			}
+			return &Inhibition{Fold: r.fold, Source: a}
+		}
+	}
+	return nil
+}
+
+func (r *rule) equals(target, source map[string]string) bool {
+	for _, l := range r.equal {
+		if target[l] != source[l] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package inhibit
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+func TestMatch(t *testing.T) {
+	i, err := New(&config.Processor{
+		InhibitRules: []*config.InhibitRule{
+			{
+				Equal:          []string{"cluster"},
+				SourceMatchers: []string{`alertname="ClusterDown"`},
+				TargetMatchers: []string{`severity=~"warning|critical"`},
+			},
+			{
+				Fold:           true,
+				SourceMatchers: []string{`alertname="NodeDown"`},
+				TargetMatchers: []string{`alertname="NodeExporterDown"`},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	clusterDown := &types.ActiveAlert{
+		Fingerprint: "a1",
+		Labels:      map[string]string{"alertname": "ClusterDown", "cluster": "eu", "severity": "critical"},
+	}
+	nodeDown := &types.ActiveAlert{
+		Fingerprint: "a2",
+		Labels:      map[string]string{"alertname": "NodeDown", "cluster": "us"},
+	}
+	active := []*types.ActiveAlert{clusterDown, nodeDown}
+
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		fingerprint string
+		active      []*types.ActiveAlert
+		want        *types.ActiveAlert
+		fold        bool
+	}{
+		{
+			name:   "not a target",
+			labels: map[string]string{"alertname": "HighLatency", "cluster": "eu", "severity": "info"},
+			active: active,
+		},
+		{
+			name:   "inhibited",
+			labels: map[string]string{"alertname": "HighLatency", "cluster": "eu", "severity": "warning"},
+			active: active,
+			want:   clusterDown,
+		},
+		{
+			name:   "equal labels differ",
+			labels: map[string]string{"alertname": "HighLatency", "cluster": "us", "severity": "warning"},
+			active: active,
+		},
+		{
+			name:   "equal label missing on target",
+			labels: map[string]string{"alertname": "HighLatency", "severity": "warning"},
+			active: active,
+		},
+		{
+			name:        "does not inhibit itself",
+			labels:      clusterDown.Labels,
+			fingerprint: clusterDown.Fingerprint,
+			active:      active,
+		},
+		{
+			name:   "folded",
+			labels: map[string]string{"alertname": "NodeExporterDown", "cluster": "eu"},
+			active: active,
+			want:   nodeDown,
+			fold:   true,
+		},
+		{
+			name:   "no active sources",
+			labels: map[string]string{"alertname": "NodeExporterDown"},
+			active: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := i.Match(tt.labels, tt.fingerprint, tt.active)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("want no inhibition, got the one by %q", got.Source.Fingerprint)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("want inhibition by %q, got none", tt.want.Fingerprint)
+			}
+			if got.Source != tt.want {
+				t.Errorf("want inhibition by %q, got %q", tt.want.Fingerprint, got.Source.Fingerprint)
+			}
+			if got.Fold != tt.fold {
+				t.Errorf("want fold %v, got %v", tt.fold, got.Fold)
+			}
+		})
+	}
+}
+
+func TestIsSourceIsTarget(t *testing.T) {
+	i, err := New(&config.Processor{
+		InhibitRules: []*config.InhibitRule{{
+			SourceMatchers: []string{`alertname="ClusterDown"`},
+			TargetMatchers: []string{`severity="warning"`},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		source bool
+		target bool
+	}{
+		{name: "source", labels: map[string]string{"alertname": "ClusterDown"}, source: true},
+		{name: "target", labels: map[string]string{"severity": "warning"}, target: true},
+		{name: "both", labels: map[string]string{"alertname": "ClusterDown", "severity": "warning"}, source: true, target: true},
+		{name: "neither", labels: map[string]string{"alertname": "HighLatency"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i.IsSource(tt.labels); got != tt.source {
+				t.Errorf("want source %v, got %v", tt.source, got)
+			}
+			if got := i.IsTarget(tt.labels); got != tt.target {
+				t.Errorf("want target %v, got %v", tt.target, got)
+			}
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  *config.InhibitRule
+		errIs error
+	}{
+		{
+			name:  "no source matchers",
+			rule:  &config.InhibitRule{TargetMatchers: []string{`severity="warning"`}},
+			errIs: ErrInhibitRuleNoMatchers,
+		},
+		{
+			name:  "no target matchers",
+			rule:  &config.InhibitRule{SourceMatchers: []string{`alertname="ClusterDown"`}},
+			errIs: ErrInhibitRuleNoMatchers,
+		},
+		{
+			name: "invalid source matcher",
+			rule: &config.InhibitRule{
+				SourceMatchers: []string{`alertname`},
+				TargetMatchers: []string{`severity="warning"`},
+			},
+			errIs: ErrInhibitRuleInvalidMatcher,
+		},
+		{
+			name: "invalid target matcher",
+			rule: &config.InhibitRule{
+				SourceMatchers: []string{`alertname="ClusterDown"`},
+				TargetMatchers: []string{`severity=~"("`},
+			},
+			errIs: ErrInhibitRuleInvalidMatcher,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&config.Processor{InhibitRules: []*config.InhibitRule{tt.rule}})
+			if !errors.Is(err, tt.errIs) {
+				t.Errorf("want error %v, got %v", tt.errIs, err)
+			}
+		})
+	}
+}
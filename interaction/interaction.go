@@ -0,0 +1,124 @@
+package interaction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+)
+
+// The IDs of the buttons that the root messages of the threads get.
+const (
+	ActionAcknowledge = "acknowledge"
+	ActionResolve     = "resolve"
+	ActionSilence1h   = "silence_1h"
+	ActionSilence4h   = "silence_4h"
+	ActionSilence24h  = "silence_24h"
+)
+
+// SilenceDurations are for how long the alert is silenced by each of the
+// silence buttons.
+var SilenceDurations = map[string]time.Duration{
+	ActionSilence1h:  time.Hour,
+	ActionSilence4h:  4 * time.Hour,
+	ActionSilence24h: 24 * time.Hour,
+}
+
+var (
+	ErrInvalidSignature  = errors.New("invalid slack request signature")
+	ErrMalformedPayload  = errors.New("malformed slack interaction payload")
+	ErrUnsupportedAction = errors.New("unsupported slack interaction")
+)
+
+// Interaction is the action taken by the user on the thread.
+type Interaction struct {
+	Action *types.ThreadAction
+	Thread types.ThreadRef
+}
+
+// Verify checks the signature of the request sent by slack.
+//
+// See: https://api.slack.com/authentication/verifying-requests-from-slack
+func Verify(signingSecret string, header http.Header, body []byte) error {
+	sv, err := slack.NewSecretsVerifier(header, signingSecret)
+	if err != nil {
+		return fmt.Errorf("%w: %w",
+			ErrInvalidSignature, err,
+		)
+	}
+	if _, err := sv.Write(body); err != nil {
+		return fmt.Errorf("%w: %w",
+			ErrInvalidSignature, err,
+		)
+	}
+	if err := sv.Ensure(); err != nil {
+		return fmt.Errorf("%w: %w",
+			ErrInvalidSignature, err,
+		)
+	}
+	return nil
+}
+
+// Parse decodes the (form-encoded) interaction payload sent by slack when
+// the user clicks one of the buttons of the thread's root message.
+func Parse(body []byte, now time.Time) (*Interaction, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w",
+			ErrMalformedPayload, err,
+		)
+	}
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		return nil, fmt.Errorf("%w: %w",
+			ErrMalformedPayload, err,
+		)
+	}
+	if callback.Type != slack.InteractionTypeBlockActions || len(callback.ActionCallback.BlockActions) == 0 {
+		return nil, fmt.Errorf("%w: %s",
+			ErrUnsupportedAction, callback.Type,
+		)
+	}
+
+	ba := callback.ActionCallback.BlockActions[0]
+	action := &types.ThreadAction{
+		At:       now,
+		UserID:   callback.User.ID,
+		UserName: callback.User.Name,
+	}
+	switch ba.ActionID {
+	case ActionAcknowledge:
+		action.Type = types.ThreadActionAcknowledge
+	case ActionResolve:
+		action.Type = types.ThreadActionResolve
+	case ActionSilence1h, ActionSilence4h, ActionSilence24h:
+		action.Type = types.ThreadActionSilence
+		action.SilencedUntil = now.Add(SilenceDurations[ba.ActionID])
+	default:
+		return nil, fmt.Errorf("%w: %s",
+			ErrUnsupportedAction, ba.ActionID,
+		)
+	}
+
+	var ref types.ThreadRef
+	if err := json.Unmarshal([]byte(ba.Value), &ref); err != nil {
+		return nil, fmt.Errorf("%w: %w",
+			ErrMalformedPayload, err,
+		)
+	}
+	if ref.Destination == "" || ref.ID == "" || ref.Topic == "" {
+		return nil, fmt.Errorf("%w: incomplete thread reference: %s",
+			ErrMalformedPayload, ba.Value,
+		)
+	}
+
+	return &Interaction{
+		Action: action,
+		Thread: ref,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package interaction
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+const testSigningSecret = "secret"
+
+// sign returns the headers that slack would send along with the body.
+func sign(secret string, at time.Time, body []byte) http.Header {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", ts)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte("payload=%7B%7D")
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		valid  bool
+	}{
+		{name: "valid", header: sign(testSigningSecret, now, body), body: body, valid: true},
+		{name: "wrong secret", header: sign("other", now, body), body: body},
+		{name: "tampered body", header: sign(testSigningSecret, now, body), body: []byte("payload=%7B%7D%20")},
+		{name: "replayed", header: sign(testSigningSecret, now.Add(-time.Hour), body), body: body},
+		{name: "unsigned", header: http.Header{}, body: body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(testSigningSecret, tt.header, tt.body)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("want %v, got %v", ErrInvalidSignature, err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	now := time.Now()
+	ref := `{\"d\":\"alerts\",\"i\":\"alert/alerts/fp\",\"t\":\"topic\"}`
+	payload := func(actionID, value string) []byte {
+		return []byte(url.Values{"payload": {`{
+			"type": "block_actions",
+			"user": {"id": "U1", "name": "jane"},
+			"actions": [{"block_id": "actions", "action_id": "` + actionID + `", "value": "` + value + `"}]
+		}`}}.Encode())
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		want    string
+		until   time.Time
+		wantErr error
+	}{
+		{name: "acknowledge", body: payload(ActionAcknowledge, ref), want: types.ThreadActionAcknowledge},
+		{name: "resolve", body: payload(ActionResolve, ref), want: types.ThreadActionResolve},
+		{
+			name:  "silence",
+			body:  payload(ActionSilence4h, ref),
+			want:  types.ThreadActionSilence,
+			until: now.Add(4 * time.Hour),
+		},
+		{name: "unknown button", body: payload("snooze", ref), wantErr: ErrUnsupportedAction},
+		{
+			name:    "other interaction",
+			body:    []byte(url.Values{"payload": {`{"type": "view_submission"}`}}.Encode()),
+			wantErr: ErrUnsupportedAction,
+		},
+		{name: "incomplete thread", body: payload(ActionAcknowledge, `{\"d\":\"alerts\"}`), wantErr: ErrMalformedPayload},
+		{name: "not json", body: []byte("payload=%7B"), wantErr: ErrMalformedPayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.body, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("want %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Action.Type != tt.want || !got.Action.SilencedUntil.Equal(tt.until) {
+				t.Errorf("want %s until %v, got %s until %v", tt.want, tt.until, got.Action.Type, got.Action.SilencedUntil)
+			}
+			if got.Action.UserID != "U1" || got.Action.UserName != "jane" || !got.Action.At.Equal(now) {
+				t.Errorf("want the action by U1 (jane) at %v, got %+v", now, got.Action)
+			}
+			if want := (types.ThreadRef{Destination: "alerts", ID: "alert/alerts/fp", Topic: "topic"}); got.Thread != want {
+				t.Errorf("want the thread %+v, got %+v", want, got.Thread)
+			}
+		})
+	}
+}
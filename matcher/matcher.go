@@ -0,0 +1,149 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Type string
+
+const (
+	TypeEqual     Type = "="
+	TypeNotEqual  Type = "!="
+	TypeRegexp    Type = "=~"
+	TypeNotRegexp Type = "!~"
+)
+
+var (
+	ErrMatcherInvalid       = errors.New("invalid matcher")
+	ErrMatcherInvalidName   = errors.New("invalid matcher label name")
+	ErrMatcherInvalidRegexp = errors.New("invalid matcher regular expression")
+	ErrMatcherInvalidValue  = errors.New("invalid matcher value")
+)
+
+var (
+	reName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// Matcher is prometheus-style label matcher (e.g. `severity=~"critical|warning"`).
+type Matcher struct {
+	Name  string
+	Type  Type
+	Value string
+
+	re *regexp.Regexp
+}
+
+// Matchers match only when all of them do.
+type Matchers []*Matcher
+
+func New(name string, typ Type, value string) (*Matcher, error) {
+	m := &Matcher{
+		Name:  name,
+		Type:  typ,
+		Value: value,
+	}
+	switch typ {
+	case TypeEqual, TypeNotEqual:
+		// noop
+	case TypeRegexp, TypeNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %s",
+				ErrMatcherInvalidRegexp, err, value,
+			)
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("%w: unknown type: %s",
+			ErrMatcherInvalid, typ,
+		)
+	}
+	return m, nil
+}
+
+// Parse parses the matcher from its textual representation.  The value may
+// be double-quoted (in which case go's escaping rules apply).
+func Parse(s string) (*Matcher, error) {
+	s = strings.TrimSpace(s)
+
+	name := reName.FindString(s)
+	if name == "" {
+		return nil, fmt.Errorf("%w: %s",
+			ErrMatcherInvalidName, s,
+		)
+	}
+	rest := strings.TrimSpace(s[len(name):])
+
+	var typ Type
+	for _, t := range []Type{TypeRegexp, TypeNotRegexp, TypeNotEqual, TypeEqual} {
+		if strings.HasPrefix(rest, string(t)) {
+			typ = t
+			break
+		}
+	}
+	if typ == "" {
+		return nil, fmt.Errorf("%w: missing or unknown operator: %s",
+			ErrMatcherInvalid, s,
+		)
+	}
+
+	value := strings.TrimSpace(rest[len(typ):])
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %s",
+				ErrMatcherInvalidValue, err, s,
+			)
+		}
+		value = unquoted
+	}
+
+	return New(name, typ, value)
+}
+
+// ParseAll parses the list of matchers.
+func ParseAll(ss []string) (Matchers, error) {
+	res := make(Matchers, 0, len(ss))
+	for _, s := range ss {
+		m, err := Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// Matches checks the value against the matcher.
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case TypeEqual:
+		return value == m.Value
+	case TypeNotEqual:
+		return value != m.Value
+	case TypeRegexp:
+		return m.re.MatchString(value)
+	case TypeNotRegexp:
+		return !m.re.MatchString(value)
+	}
+	return false
+}
+
+func (m *Matcher) String() string {
+	return m.Name + string(m.Type) + strconv.Quote(m.Value)
+}
+
+// Matches checks whether all matchers match the labels.  Absent labels are
+// treated as if they had an empty value (same as prometheus does).
+func (ms Matchers) Matches(labels map[string]string) bool {
+	for _, m := range ms {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
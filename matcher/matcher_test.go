@@ -0,0 +1,95 @@
+package matcher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in     string
+		name   string
+		typ    Type
+		value  string
+		errIs  error
+		string string
+	}{
+		{in: `severity="critical"`, name: "severity", typ: TypeEqual, value: "critical", string: `severity="critical"`},
+		{in: `severity=critical`, name: "severity", typ: TypeEqual, value: "critical", string: `severity="critical"`},
+		{in: ` env != "prod" `, name: "env", typ: TypeNotEqual, value: "prod", string: `env!="prod"`},
+		{in: `severity=~"info|none"`, name: "severity", typ: TypeRegexp, value: "info|none", string: `severity=~"info|none"`},
+		{in: `job!~"node.*"`, name: "job", typ: TypeNotRegexp, value: "node.*", string: `job!~"node.*"`},
+		{in: `msg="a \"quoted\" value"`, name: "msg", typ: TypeEqual, value: `a "quoted" value`, string: `msg="a \"quoted\" value"`},
+		{in: `empty=""`, name: "empty", typ: TypeEqual, value: "", string: `empty=""`},
+		{in: `1abc="x"`, errIs: ErrMatcherInvalidName},
+		{in: `="x"`, errIs: ErrMatcherInvalidName},
+		{in: `severity`, errIs: ErrMatcherInvalid},
+		{in: `severity>"x"`, errIs: ErrMatcherInvalid},
+		{in: `severity="unterminated`, errIs: ErrMatcherInvalidValue},
+		{in: `severity=~"("`, errIs: ErrMatcherInvalidRegexp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			m, err := Parse(tt.in)
+			if tt.errIs != nil {
+				if !errors.Is(err, tt.errIs) {
+					t.Fatalf("want error %v, got %v", tt.errIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Name != tt.name || m.Type != tt.typ || m.Value != tt.value {
+				t.Errorf("want %s %s %q, got %s %s %q", tt.name, tt.typ, tt.value, m.Name, m.Type, m.Value)
+			}
+			if got := m.String(); got != tt.string {
+				t.Errorf("want string %s, got %s", tt.string, got)
+			}
+		})
+	}
+}
+
+func TestMatchersMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		matchers []string
+		labels   map[string]string
+		want     bool
+	}{
+		{name: "no matchers", labels: map[string]string{"a": "b"}, want: true},
+		{name: "equal", matchers: []string{`severity="critical"`}, labels: map[string]string{"severity": "critical"}, want: true},
+		{name: "equal mismatch", matchers: []string{`severity="critical"`}, labels: map[string]string{"severity": "warning"}, want: false},
+		{name: "not equal", matchers: []string{`env!="prod"`}, labels: map[string]string{"env": "dev"}, want: true},
+		{name: "absent label is empty", matchers: []string{`env=""`}, labels: map[string]string{}, want: true},
+		{name: "absent label not equal", matchers: []string{`env!="prod"`}, labels: map[string]string{}, want: true},
+		{name: "regexp is anchored", matchers: []string{`job=~"node"`}, labels: map[string]string{"job": "node-exporter"}, want: false},
+		{name: "regexp", matchers: []string{`job=~"node.*"`}, labels: map[string]string{"job": "node-exporter"}, want: true},
+		{name: "not regexp", matchers: []string{`job!~"node.*"`}, labels: map[string]string{"job": "node-exporter"}, want: false},
+		{
+			name:     "all must match",
+			matchers: []string{`severity="critical"`, `env="prod"`},
+			labels:   map[string]string{"severity": "critical", "env": "dev"},
+			want:     false,
+		},
+		{
+			name:     "all match",
+			matchers: []string{`severity="critical"`, `env=~"prod|staging"`},
+			labels:   map[string]string{"severity": "critical", "env": "staging"},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, err := ParseAll(tt.matchers)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ms.Matches(tt.labels); got != tt.want {
+				t.Errorf("want %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
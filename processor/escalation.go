@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/escalation"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/publisher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/router"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"go.uber.org/zap"
+)
+
+// escalateThread takes the next step of the escalation policy of the alert of
+// the thread if it is due, and if nobody has acknowledged the alert by then
+// (the acknowledgement reactions are only checked at that point).  The
+// failures are only logged (the next tick retries).
+func (p *Processor) escalateThread(ctx context.Context, thread *types.Thread, now time.Time) {
+	if thread.TS == "" || thread.Alert == nil || thread.Status() != types.AlertStatusFiring {
+		return
+	}
+	if thread.Resolved() || thread.IsFlapping() || thread.Acknowledged() {
+		return
+	}
+	current := thread.Escalation
+	if current != nil && !current.Since.Equal(thread.StatusChangedAt) {
+		current = nil // the alert has fired again since it was escalated
+	}
+	step := p.escalator.Next(router.Labels(nil, thread.Alert), current)
+	if step == nil || now.Sub(thread.StatusChangedAt) < step.After {
+		return
+	}
+	pub, known := p.publishers[threadDestination(thread.ID)]
+	if !known {
+		return // the channel is not configured anymore
+	}
+
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+		zap.String("escalation_policy", step.Policy),
+		zap.String("slack_thread_id", thread.ID),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	if rpub, ok := pub.(publisher.ReactionReader); ok && len(p.escalator.AckReactions()) > 0 {
+		// if the reactions can not be checked, it's better to escalate anyway
+		userID, err := rpub.ReactedBy(ctx, thread.TS, p.escalator.AckReactions())
+		if err == nil && userID != "" {
+			p.acknowledgeThread(ctx, pub, thread, userID, now)
+			return
+		}
+	}
+
+	next := &types.Escalation{
+		EscalatedAt: now.UTC(),
+		Level:       step.Level,
+		Since:       thread.StatusChangedAt.UTC(),
+	}
+	escalated, err := p.db.SetSlackThreadEscalation(ctx, thread.Topic, thread.ID, thread.Escalation, next)
+	if err != nil || !escalated {
+		// someone else is escalating
+		return
+	}
+	if err := p.publishEscalation(ctx, pub, thread, step, now); err != nil {
+		// let the next tick try again
+		_, _ = p.db.SetSlackThreadEscalation(ctx, thread.Topic, thread.ID, next, thread.Escalation)
+		return
+	}
+	l.Info("Escalated the alert that nobody has acknowledged",
+		zap.Int("escalation_level", step.Level),
+	)
+}
+
+// acknowledgeThread records the acknowledgement of the alert of the thread by
+// the user who reacted to its root message, and refreshes the root message.
+func (p *Processor) acknowledgeThread(
+	ctx context.Context,
+	pub publisher.Publisher,
+	thread *types.Thread,
+	userID string,
+	now time.Time,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	action := &types.ThreadAction{
+		At:     now,
+		Type:   types.ThreadActionAcknowledge,
+		UserID: userID,
+	}
+	updated, err := p.db.SetSlackThreadAction(ctx, thread.Topic, thread.ID, action)
+	if err != nil {
+		l.Error("Failed to record the acknowledgement of the thread",
+			zap.Error(err),
+		)
+		return
+	}
+	updated.ID = thread.ID
+	updated.Topic = thread.Topic
+
+	l.Info("Recorded the acknowledgement of the thread by reaction",
+		zap.String("slack_user_id", userID),
+	)
+
+	pub.UpdateThread(ctx, nil, updated)
+}
+
+// publishEscalation mentions the step's users and user groups in the thread,
+// and links the thread in the step's channel (if there is one).  Only the
+// failure to post into the thread is returned, so that the retries do not
+// spam the escalation channel.
+func (p *Processor) publishEscalation(
+	ctx context.Context,
+	pub publisher.Publisher,
+	thread *types.Thread,
+	step *escalation.Step,
+	now time.Time,
+) error {
+	unacknowledged := now.Sub(thread.StatusChangedAt).Round(time.Minute)
+
+	text := fmt.Sprintf(":rotating_light: Not acknowledged for %s, escalating", unacknowledged)
+	if step.ChannelName != "" {
+		text += " to #" + step.ChannelName
+	}
+	if step.Mentions != "" {
+		text = step.Mentions + " " + text
+	}
+	if _, err := pub.PublishNote(ctx, thread.TS, text); err != nil {
+		return err
+	}
+
+	if step.ChannelName == "" {
+		return nil
+	}
+	text = fmt.Sprintf(":rotating_light: *%s* has not been acknowledged in %s for %s",
+		thread.Alert.Labels["alertname"], pub.Name(), unacknowledged,
+	)
+	if lpub, ok := pub.(publisher.Permalinker); ok {
+		// the errors are logged by the publishers
+		link, err := lpub.Permalink(ctx, thread.TS)
+		if err != nil {
+			return nil
+		}
+		text += ": " + link
+	}
+	if step.Mentions != "" {
+		text = step.Mentions + " " + text
+	}
+	_, _ = p.channel(step.ChannelName).PublishNote(ctx, "", text)
+	return nil
+}
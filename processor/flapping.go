@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/publisher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"go.uber.org/zap"
+)
+
+// trackFlapping returns the track of the status changes of the alert of the
+// thread updated with the alert (or nil if the detection is disabled, or if
+// there is no thread yet).
+func (p *Processor) trackFlapping(thread *types.Thread, alert *types.Alert, now time.Time) *types.Flapping {
+	if p.flapping.Transitions <= 0 || thread == nil {
+		return nil
+	}
+
+	f := thread.Flapping.Clone()
+	if f == nil {
+		f = &types.Flapping{}
+	}
+
+	// the transitions that are out of the window do not count
+	f.Transitions = slices.DeleteFunc(f.Transitions, func(t time.Time) bool {
+		return now.Sub(t) > p.flapping.Window
+	})
+
+	if thread.Status() != "" && thread.Status() != alert.Status {
+		f.Transitions = append(f.Transitions, now)
+		f.LastTransitionAt = now
+		if f.IsFlapping() {
+			f.Changes++
+		}
+	}
+
+	if !f.IsFlapping() && len(f.Transitions) >= p.flapping.Transitions {
+		f.Since = now
+		f.Changes = len(f.Transitions)
+	}
+
+	return f
+}
+
+// settleFlapping posts the summary into the thread of the flapping alert that
+// has kept its status for long enough, and clears the flapping state of the
+// thread.  It returns true if the alert has settled.
+func (p *Processor) settleFlapping(
+	ctx context.Context,
+	topic string,
+	pub publisher.Publisher,
+	threadID string,
+	thread *types.Thread,
+	now time.Time,
+) bool {
+	l := logutils.LoggerFromContext(ctx)
+
+	if !thread.IsFlapping() || now.Sub(thread.Flapping.LastTransitionAt) < p.flapping.StablePeriod {
+		return false
+	}
+
+	noteID := fmt.Sprintf("flapping/%s/%d", threadID, thread.Flapping.Since.Unix())
+	if locked, err := p.db.LockSlackMessage(ctx, topic, noteID); !locked || err != nil {
+		// someone else is settling it
+		return false
+	}
+
+	text := fmt.Sprintf(":repeat: Stopped flapping: the status changed %d times since %s (%d updates suppressed), it is *%s* now",
+		thread.Flapping.Changes,
+		thread.Flapping.Since.UTC().Format(time.RFC3339),
+		thread.Flapping.Suppressed,
+		strings.ToUpper(thread.Status()),
+	)
+	noteTS, err := pub.PublishNote(ctx, thread.TS, text)
+	if err != nil {
+		_ = p.db.UnlockSlackMessage(ctx, topic, noteID)
+		return false
+	}
+	_ = p.db.SetSlackMessageTS(ctx, topic, noteID, noteTS)
+
+	settled := &types.Flapping{
+		LastTransitionAt: thread.Flapping.LastTransitionAt,
+	}
+	if err := p.db.SetSlackThreadFlapping(ctx, topic, threadID, settled); err != nil {
+		l.Warn("Failed to clear the flapping state of the thread",
+			zap.Error(err),
+		)
+	}
+	thread.Flapping = settled
+
+	l.Info("The alert stopped flapping")
+	return true
+}
+
+// checkFlappingSettled settles the flapping alert of the thread if it has
+// kept its status for long enough (the alerts that do not change are
+// duplicates, so this is checked for them separately).
+func (p *Processor) checkFlappingSettled(
+	ctx context.Context,
+	topic string,
+	pub publisher.Publisher,
+	message *types.Message,
+	threadID string,
+) {
+	if p.flapping.Transitions <= 0 {
+		return
+	}
+	thread, err := p.db.GetSlackThread(ctx, topic, threadID)
+	if err != nil || thread == nil || thread.TS == "" {
+		return
+	}
+	if p.settleFlapping(ctx, topic, pub, threadID, thread, time.Now()) {
+		thread.ID = threadID
+		thread.Topic = topic
+		pub.UpdateThread(ctx, message, thread)
+	}
+}
@@ -0,0 +1,226 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+	"github.com/flashbots/prometheus-sns-lambda-slack/interaction"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/publisher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/silence"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInteractionsDisabled = errors.New("slack interactions are not enabled (signing secret is not configured)")
+	ErrThreadWithoutAlert   = errors.New("slack thread has no alert")
+	ErrUnknownDestination   = errors.New("unknown destination")
+)
+
+// HandleInteraction handles the request sent by slack when the user clicks
+// one of the buttons of the root message of the thread: it records the
+// action in the db (creating the silence, if requested), and refreshes the
+// root message.
+func (p *Processor) HandleInteraction(ctx context.Context, header http.Header, body []byte) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	if p.signingSecret == "" {
+		return types.Permanent(ErrInteractionsDisabled)
+	}
+	if err := interaction.Verify(p.signingSecret, header, body); err != nil {
+		l.Warn("Rejected slack interaction",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	i, err := interaction.Parse(body, time.Now())
+	if err != nil {
+		l.Error("Error un-marshalling slack interaction",
+			zap.Error(err),
+		)
+		return types.Malformed(err)
+	}
+
+	l = l.With(
+		zap.String("destination", i.Thread.Destination),
+		zap.String("slack_thread_id", i.Thread.ID),
+		zap.String("slack_user_id", i.Action.UserID),
+		zap.String("thread_action", i.Action.Type),
+		zap.String("topic", i.Thread.Topic),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	pub, known := p.publishers[i.Thread.Destination]
+	if !known {
+		return types.Permanent(fmt.Errorf("%w: %s",
+			ErrUnknownDestination, i.Thread.Destination,
+		))
+	}
+
+	if i.Action.Type == types.ThreadActionSilence {
+		// the silence must be in place before the thread is marked as
+		// silenced (otherwise the failures would leave it marked in vain)
+		if err := p.createSilence(ctx, &i.Thread, i.Action); err != nil {
+			return err
+		}
+	}
+
+	thread, err := p.db.SetSlackThreadAction(ctx, i.Thread.Topic, i.Thread.ID, i.Action)
+	if err != nil {
+		l.Error("Failed to record the action on the thread",
+			zap.Error(err),
+		)
+		return err
+	}
+	thread.ID = i.Thread.ID
+	thread.Topic = i.Thread.Topic
+
+	l.Info("Recorded the action on the thread",
+		zap.Any("thread_action", i.Action),
+	)
+
+	if i.Action.Type == types.ThreadActionSilence && p.alertmanager != nil {
+		p.linkUpstreamSilence(ctx, pub, thread, i.Action)
+	}
+
+	pub.UpdateThread(ctx, nil, thread)
+
+	return nil
+}
+
+// createSilence creates the silence requested by the action on the thread
+// (in alertmanager, if it is configured, or in the db otherwise), and records
+// its ID (and url) with the action.
+func (p *Processor) createSilence(
+	ctx context.Context,
+	ref *types.ThreadRef,
+	action *types.ThreadAction,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	thread, err := p.db.GetSlackThread(ctx, ref.Topic, ref.ID)
+	if err != nil {
+		return err
+	}
+	if thread == nil {
+		return types.Permanent(db.ErrThreadNotFound)
+	}
+	if thread.Alert == nil {
+		return types.Permanent(ErrThreadWithoutAlert)
+	}
+
+	s := &types.Silence{
+		Comment:   "silenced from slack thread " + thread.TS,
+		CreatedAt: action.At,
+		CreatedBy: action.UserName,
+		EndsAt:    action.SilencedUntil,
+		Matchers:  p.silenceMatchers(thread.Alert),
+		StartsAt:  action.At,
+	}
+	if err := silence.Validate(s); err != nil {
+		return types.Permanent(err)
+	}
+
+	if p.alertmanager != nil {
+		id, err := p.alertmanager.CreateSilence(ctx, s)
+		if err != nil {
+			l.Error("Failed to create the silence in alertmanager",
+				zap.Error(err),
+			)
+			return err
+		}
+		action.SilenceID = id
+		action.SilenceURL = p.alertmanager.SilenceURL(id)
+		return nil
+	}
+
+	// alertmanager assigns the IDs to its silences by itself
+	s.ID = uuid.New().String()
+	if err := p.db.AddSilence(ctx, s); err != nil {
+		l.Error("Failed to add the silence",
+			zap.Error(err),
+		)
+		return err
+	}
+	p.silencer.Invalidate()
+	action.SilenceID = s.ID
+	return nil
+}
+
+// linkUpstreamSilence links the silence created in alertmanager in the
+// thread.
+func (p *Processor) linkUpstreamSilence(
+	ctx context.Context,
+	pub publisher.Publisher,
+	thread *types.Thread,
+	action *types.ThreadAction,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	silenceRef := "`" + action.SilenceID + "`"
+	if action.SilenceURL != "" {
+		silenceRef = "<" + action.SilenceURL + "|" + action.SilenceID + ">"
+	}
+	text := fmt.Sprintf(":no_bell: Silenced by <@%s> until %s in alertmanager: %s",
+		action.UserID, action.SilencedUntil.UTC().Format(time.RFC3339), silenceRef,
+	)
+	if _, err := pub.PublishNote(ctx, thread.TS, text); err != nil {
+		l.Warn("Failed to link alertmanager's silence in the thread",
+			zap.Error(err),
+		)
+	}
+}
+
+// silenceMatchers returns the matchers that silence the alert of the thread
+// (and the ones that would end up in the same thread).
+func (p *Processor) silenceMatchers(alert *types.Alert) []string {
+	if alert == nil {
+		return nil
+	}
+	names := slices.Clone(p.thread.IdentityLabels)
+	if len(names) == 0 {
+		names = make([]string, 0, len(alert.Labels))
+		for name := range alert.Labels {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		m, err := matcher.New(name, matcher.TypeEqual, alert.Labels[name])
+		if err != nil {
+			continue
+		}
+		res = append(res, m.String())
+	}
+	return res
+}
+
+// InteractionStatus returns the http status code that reports the outcome
+// of HandleInteraction back to slack.
+func InteractionStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if errors.Is(err, interaction.ErrInvalidSignature) {
+		return http.StatusUnauthorized
+	}
+	switch types.Kind(err) {
+	case types.ErrMalformed:
+		return http.StatusBadRequest
+	case types.ErrPermanent:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -2,15 +2,97 @@ package processor
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
 	"github.com/flashbots/prometheus-sns-lambda-slack/types"
 	"go.uber.org/zap"
 )
 
+const (
+	eventSourceSNS = "aws:sns"
+	eventSourceSQS = "aws:sqs"
+
+	eventSourceSchedule = "aws.events"
+)
+
+var (
+	ErrUnsupportedEvent = errors.New("unsupported lambda event")
+)
+
+// LambdaHandler dispatches the lambda event to the handler that corresponds
+// to its source (SNS, SQS, the function url that slack sends the
+// interactions to, or EventBridge's schedule).
+func (p *Processor) LambdaHandler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var event struct {
+		Records []struct {
+			EventSource string `json:"eventSource"` // json is case-insensitive, so SNS's `EventSource` fits as well
+		} `json:"Records"`
+		RequestContext struct {
+			HTTP struct {
+				Method string `json:"method"`
+			} `json:"http"`
+		} `json:"requestContext"`
+		Source string `json:"source"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %w",
+			ErrUnsupportedEvent, err,
+		)
+	}
+
+	if event.RequestContext.HTTP.Method != "" {
+		var e events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return p.LambdaFunctionURL(ctx, e)
+	}
+
+	if event.Source == eventSourceSchedule {
+		var e events.CloudWatchEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return nil, p.LambdaSchedule(ctx, e)
+	}
+
+	source := ""
+	if len(event.Records) > 0 {
+		source = event.Records[0].EventSource
+	}
+
+	switch source {
+	case eventSourceSNS:
+		var e events.SNSEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return nil, p.Lambda(ctx, e)
+
+	case eventSourceSQS:
+		var e events.SQSEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return p.LambdaSQS(ctx, e)
+
+	default:
+		p.log.Error("Received unsupported lambda event",
+			zap.String("event", strings.Replace(string(payload), "\n", " ", -1)),
+		)
+		return nil, fmt.Errorf("%w: %s",
+			ErrUnsupportedEvent, source,
+		)
+	}
+}
+
 func (p *Processor) Lambda(ctx context.Context, event events.SNSEvent) error {
 	l := p.log
 	defer l.Sync() //nolint:errcheck
@@ -23,7 +105,7 @@ func (p *Processor) Lambda(ctx context.Context, event events.SNSEvent) error {
 				zap.String("message", strings.Replace(r.SNS.Message, "\n", " ", -1)),
 				zap.Error(err),
 			)
-			errs = append(errs, err)
+			errs = append(errs, types.Malformed(err))
 			continue
 		}
 		if err := p.ProcessMessage(ctx, r.SNS.TopicArn, &m); err != nil {
@@ -31,8 +113,140 @@ func (p *Processor) Lambda(ctx context.Context, event events.SNSEvent) error {
 		}
 	}
 
-	if len(errs) != 0 {
-		return errors.Join(errs...)
+	// SNS re-delivers the whole event on error, so we only fail if at least
+	// one of the failures is worth retrying
+	err := errors.Join(errs...)
+	if types.IsRetriable(err) {
+		return err
+	}
+	if kind := types.Kind(err); kind == types.ErrMalformed || kind == types.ErrPermanent {
+		l.Error("Dropped the event since retrying would not help",
+			zap.Error(err),
+		)
 	}
 	return nil
 }
+
+// LambdaSQS handles the messages that SNS delivers via SQS queue.  Only the
+// records that failed are reported back, so that SQS retries just them (and
+// eventually moves them to the dead-letter queue).  The duplicates are not
+// failures, while the malformed and permanently failing records are reported
+// so that they end up in the dead-letter queue for inspection.
+func (p *Processor) LambdaSQS(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	l := p.log
+	defer l.Sync() //nolint:errcheck
+
+	res := events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{},
+	}
+	for _, r := range event.Records {
+		l := l.With(
+			zap.String("sqs_message_id", r.MessageId),
+		)
+		ctx := logutils.ContextWithLogger(ctx, l)
+
+		topic, m, err := decodeSQSMessage(&r)
+		if err != nil {
+			l.Error("Error un-marshalling message",
+				zap.String("message", strings.Replace(r.Body, "\n", " ", -1)),
+				zap.Error(err),
+			)
+			res.BatchItemFailures = append(res.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: r.MessageId,
+			})
+			continue
+		}
+		err = p.ProcessMessage(ctx, topic, m)
+		if !types.IsRetriable(err) {
+			kind := types.Kind(err)
+			if kind != types.ErrMalformed && kind != types.ErrPermanent {
+				continue
+			}
+			// retrying would not help, it is only reported so that the
+			// record ends up in the dead-letter queue
+			l.Error("Gave up on the record since retrying would not help",
+				zap.Error(err),
+			)
+		}
+		res.BatchItemFailures = append(res.BatchItemFailures, events.SQSBatchItemFailure{
+			ItemIdentifier: r.MessageId,
+		})
+	}
+
+	return res, nil
+}
+
+// LambdaFunctionURL handles the slack interactions (the clicks on the buttons
+// of the root messages of the threads) delivered via lambda's function url.
+func (p *Processor) LambdaFunctionURL(ctx context.Context, request events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	l := p.log.With(
+		zap.String("request_id", request.RequestContext.RequestID),
+	)
+	defer l.Sync() //nolint:errcheck
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	if request.RequestContext.HTTP.Method != http.MethodPost {
+		return events.LambdaFunctionURLResponse{
+			StatusCode: http.StatusMethodNotAllowed,
+		}, nil
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(request.Body)
+		if err != nil {
+			l.Error("Error decoding request body",
+				zap.Error(err),
+			)
+			return events.LambdaFunctionURLResponse{
+				StatusCode: http.StatusBadRequest,
+			}, nil
+		}
+		body = decoded
+	}
+
+	header := make(http.Header, len(request.Headers))
+	for k, v := range request.Headers {
+		header.Set(k, v) // function url passes them lower-cased
+	}
+
+	err := p.HandleInteraction(ctx, header, body)
+	res := events.LambdaFunctionURLResponse{
+		StatusCode: InteractionStatus(err),
+	}
+	if err != nil {
+		res.Body = err.Error()
+	}
+	return res, nil
+}
+
+// LambdaSchedule runs the periodic chores on EventBridge's schedule.
+func (p *Processor) LambdaSchedule(ctx context.Context, event events.CloudWatchEvent) error {
+	l := p.log.With(
+		zap.String("event_id", event.ID),
+	)
+	defer l.Sync() //nolint:errcheck
+
+	return p.Tick(logutils.ContextWithLogger(ctx, l))
+}
+
+// decodeSQSMessage extracts the alertmanager's message (and the topic it was
+// published to) from SNS envelope in the body of SQS message.  If the SNS
+// subscription has raw message delivery enabled, the body is the message
+// itself (and the ARN of the queue is used as the topic).
+func decodeSQSMessage(r *events.SQSMessage) (string, *types.Message, error) {
+	var envelope events.SNSEntity
+	if err := json.Unmarshal([]byte(r.Body), &envelope); err == nil && envelope.Type == "Notification" {
+		var m types.Message
+		if err := json.Unmarshal([]byte(envelope.Message), &m); err != nil {
+			return "", nil, err
+		}
+		return envelope.TopicArn, &m, nil
+	}
+
+	var m types.Message
+	if err := json.Unmarshal([]byte(r.Body), &m); err != nil {
+		return "", nil, err
+	}
+	return r.EventSourceARN, &m, nil
+}
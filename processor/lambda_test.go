@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+func TestLambdaRetryDecision(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		failure error // of the publish
+		retry   bool  // the SNS event is redelivered
+		report  bool  // the SQS record is reported as failed
+	}{
+		{name: "published", retry: false, report: false},
+		{name: "malformed", body: "{", retry: false, report: true},
+		{name: "transient failure", failure: types.Transient(errors.New("timeout")), retry: true, report: true},
+		{name: "permanent failure", failure: types.Permanent(errors.New("channel_not_found")), retry: false, report: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			if body == "" {
+				b, err := json.Marshal(testMessage(types.AlertStatusFiring, "A"))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				body = string(b)
+			}
+			ctx := context.Background()
+
+			p, pub := newTestProcessor(t, nil)
+			if tt.failure != nil {
+				pub.failures = []error{tt.failure}
+			}
+			err := p.Lambda(ctx, events.SNSEvent{Records: []events.SNSEventRecord{
+				{SNS: events.SNSEntity{Message: body, TopicArn: testTopic}},
+			}})
+			if retry := err != nil; retry != tt.retry {
+				t.Errorf("sns: want the redelivery %v, got %v", tt.retry, err)
+			}
+
+			p, pub = newTestProcessor(t, nil)
+			if tt.failure != nil {
+				pub.failures = []error{tt.failure}
+			}
+			res, err := p.LambdaSQS(ctx, events.SQSEvent{Records: []events.SQSMessage{
+				{Body: body, EventSourceARN: testTopic, MessageId: "1"},
+			}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if report := len(res.BatchItemFailures) > 0; report != tt.report {
+				t.Errorf("sqs: want the failure reported %v, got %+v", tt.report, res.BatchItemFailures)
+			}
+		})
+	}
+}
+
+func TestLambdaDropsDuplicates(t *testing.T) {
+	p, pub := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	message := testMessage(types.AlertStatusFiring, "A")
+	b, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// another replica holds the lock of the message
+	if _, err := p.db.LockSlackMessage(ctx, testTopic, messageID(pub, &message.Alerts[0])); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = p.Lambda(ctx, events.SNSEvent{Records: []events.SNSEventRecord{
+		{SNS: events.SNSEntity{Message: string(b), TopicArn: testTopic}},
+	}})
+	if err != nil {
+		t.Errorf("sns: want no redelivery, got %v", err)
+	}
+	res, err := p.LambdaSQS(ctx, events.SQSEvent{Records: []events.SQSMessage{
+		{Body: string(b), EventSourceARN: testTopic, MessageId: "1"},
+	}})
+	if err != nil || len(res.BatchItemFailures) != 0 {
+		t.Errorf("sqs: want no failures reported, got %+v, %v", res.BatchItemFailures, err)
+	}
+	if pub.calls != 0 {
+		t.Errorf("want nothing published, got %d calls", pub.calls)
+	}
+}
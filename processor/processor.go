@@ -3,37 +3,127 @@ package processor
 import (
 	"context"
 	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
 	"time"
 
+	"github.com/flashbots/prometheus-sns-lambda-slack/alertmanager"
 	"github.com/flashbots/prometheus-sns-lambda-slack/config"
 	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+	"github.com/flashbots/prometheus-sns-lambda-slack/escalation"
+	"github.com/flashbots/prometheus-sns-lambda-slack/filter"
+	"github.com/flashbots/prometheus-sns-lambda-slack/inhibit"
 	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
 	"github.com/flashbots/prometheus-sns-lambda-slack/publisher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/ratelimit"
+	"github.com/flashbots/prometheus-sns-lambda-slack/router"
+	"github.com/flashbots/prometheus-sns-lambda-slack/silence"
 	"github.com/flashbots/prometheus-sns-lambda-slack/types"
 	"go.uber.org/zap"
 )
 
+const (
+	// activeAlertExpiry is for how long the firing alert inhibits the others
+	// if its resolution never comes (alertmanager re-sends the alerts that
+	// are still firing every `repeat_interval`, well before that).
+	activeAlertExpiry = 24 * time.Hour
+)
+
 var (
-	ErrAlreadyLocked = errors.New("the message is already locked, let's retry later")
+	ErrAlreadyLocked = errors.New("the message is already locked by someone else")
 )
 
 type Processor struct {
-	db          *db.DB
-	ignoreRules map[string]struct{}
-	log         *zap.Logger
-	slack       *publisher.SlackChannel
+	alertmanager *alertmanager.Client
+	db           db.DB
+	escalator    *escalation.Escalator
+	filter       *filter.Filter
+	flapping     config.Flapping
+	inhibitor    *inhibit.Inhibitor
+	limiter      *ratelimit.Limiter
+	log          *zap.Logger
+	publishers   map[string]publisher.Publisher // by their IDs
+	reminders    []*config.Reminder
+	router       *router.Router
+	silencer     *silence.Silencer
+	thread       config.Thread
+
+	digest        bool
+	quietResolved bool
+	signingSecret string
+	silenceNotes  bool
 }
 
 func New(cfg *config.Config) (*Processor, error) {
-	d, err := db.New(cfg.Processor.DynamoDBName)
+	d, err := db.New(&cfg.Processor)
+	if err != nil {
+		return nil, err
+	}
+	f, err := filter.New(&cfg.Processor)
+	if err != nil {
+		return nil, err
+	}
+	i, err := inhibit.New(&cfg.Processor)
 	if err != nil {
 		return nil, err
 	}
+	r, err := router.New(&cfg.Slack)
+	if err != nil {
+		return nil, err
+	}
+	t, err := publisher.NewTemplates(&cfg.Slack.Templates)
+	if err != nil {
+		return nil, err
+	}
+	m, err := publisher.NewMentions(cfg.Slack.Mentions)
+	if err != nil {
+		return nil, err
+	}
+	rl, err := ratelimit.New(&cfg.Processor.RateLimits, d)
+	if err != nil {
+		return nil, err
+	}
+	e, err := escalation.New(&cfg.Processor.Escalation)
+	if err != nil {
+		return nil, err
+	}
+	var am *alertmanager.Client
+	if cfg.Alertmanager.URL != "" {
+		if am, err = alertmanager.New(&cfg.Alertmanager); err != nil {
+			return nil, err
+		}
+	}
+	publishers := make(map[string]publisher.Publisher)
+	for _, c := range r.Channels() {
+		pub := publisher.NewSlackChannel(cfg, t, m, c.ID, c.Name)
+		publishers[pub.ID()] = pub
+	}
+	for _, c := range e.Channels() {
+		if _, exists := publishers[publisher.SlackChannelID(c.Name)]; !exists {
+			pub := publisher.NewSlackChannel(cfg, t, m, c.ID, c.Name)
+			publishers[pub.ID()] = pub
+		}
+	}
 	return &Processor{
-		db:          d,
-		ignoreRules: cfg.Processor.IgnoreRules,
-		log:         zap.L(),
-		slack:       publisher.NewSlackChannel(cfg),
+		alertmanager: am,
+		db:           d,
+		escalator:    e,
+		filter:       f,
+		flapping:     cfg.Processor.Flapping,
+		inhibitor:    i,
+		limiter:      rl,
+		log:          zap.L(),
+		publishers:   publishers,
+		reminders:    cfg.Processor.Reminders,
+		router:       r,
+		silencer:     silence.New(d),
+		thread:       cfg.Processor.Thread,
+
+		digest:        cfg.Processor.Digest,
+		quietResolved: cfg.Slack.QuietResolved,
+		signingSecret: cfg.Slack.SigningSecret,
+		silenceNotes:  cfg.Processor.SilenceNotes,
 	}, nil
 }
 
@@ -42,8 +132,15 @@ func (p *Processor) ProcessMessage(
 	topic string,
 	message *types.Message,
 ) error {
-	errs := []error{}
-	for _, alert := range message.Alerts {
+	if message.TruncatedAlerts > 0 {
+		logutils.LoggerFromContext(ctx).Warn("Alertmanager truncated the alerts in the message",
+			zap.Int("truncated_alerts", message.TruncatedAlerts),
+			zap.String("group_key", message.GroupKey),
+		)
+	}
+
+	for i := range message.Alerts {
+		alert := &message.Alerts[i]
 		for k, v := range message.CommonAnnotations {
 			if _, present := alert.Annotations[k]; !present {
 				alert.Annotations[k] = v
@@ -54,14 +151,17 @@ func (p *Processor) ProcessMessage(
 				alert.Labels[k] = v
 			}
 		}
-		_timestamp, err := time.Parse("2006-01-02T15:04:05Z07:00", alert.StartsAt) // Grafana
-		if err != nil {
-			_timestamp, err = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", alert.StartsAt) // Prometheus
-		}
-		if err == nil {
-			alert.StartsAt = _timestamp.Format("2006-01-02T15:04:05Z07:00")
-		}
-		if err := p.processAlert(ctx, topic, &alert); err != nil {
+		alert.StartsAt = normalizeTimestamp(alert.StartsAt)
+		alert.EndsAt = normalizeTimestamp(alert.EndsAt)
+	}
+
+	if p.digest {
+		return p.processGroup(ctx, topic, message)
+	}
+
+	errs := []error{}
+	for i := range message.Alerts {
+		if err := p.processAlert(ctx, topic, message, &message.Alerts[i]); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -74,85 +174,606 @@ func (p *Processor) ProcessMessage(
 func (p *Processor) processAlert(
 	ctx context.Context,
 	topic string,
+	message *types.Message,
 	alert *types.Alert,
-) (err error) {
+) error {
+	ctx = p.alertContext(ctx, alert)
+
+	labels, foldInto, publish := p.screenAlert(ctx, topic, message, alert)
+	if !publish {
+		return nil
+	}
+
+	errs := []error{}
+	for _, channel := range p.router.Route(labels) {
+		pub := p.channel(channel.Name)
+		foldThreadID := ""
+		if foldInto != "" {
+			foldThreadID = threadID(pub, foldInto)
+		}
+		if err := p.publishAlert(ctx, topic, pub, message, alert, foldThreadID); err != nil {
+			errs = append(errs, err)
+		}
+		p.reportSuppressed(ctx, pub)
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// processGroup publishes the alerts of the message into the digest thread of
+// their alertmanager's group (one per destination).
+func (p *Processor) processGroup(
+	ctx context.Context,
+	topic string,
+	message *types.Message,
+) error {
 	l := logutils.LoggerFromContext(ctx).With(
-		zap.String("alert_fingerprint", alert.Fingerprint()),
-		zap.String("alert_labels_fingerprint", alert.LabelsFingerprint()),
+		zap.String("group_fingerprint", message.GroupFingerprint()),
+		zap.String("group_key", message.GroupKey),
 	)
 	ctx = logutils.ContextWithLogger(ctx, l)
 
-	slackMessageID := p.slackMessageID(alert)
-	slackThreadID := p.slackThreadID(alert)
-	slackThreadTS := ""
+	channels := []string{}
+	routed := make(map[string][]*types.Alert)
+	for i := range message.Alerts {
+		alert := &message.Alerts[i]
+		// the digest thread is where the folded alerts go anyway
+		labels, _, publish := p.screenAlert(p.alertContext(ctx, alert), topic, message, alert)
+		if !publish {
+			continue
+		}
+		for _, channel := range p.router.Route(labels) {
+			if _, seen := routed[channel.Name]; !seen {
+				channels = append(channels, channel.Name)
+			}
+			routed[channel.Name] = append(routed[channel.Name], alert)
+		}
+	}
 
-	if _, ignore := p.ignoreRules[alert.Labels["alertname"]]; ignore {
-		l.Info("Skipped the alert according to ignore-rules configuration",
+	errs := []error{}
+	for _, channel := range channels {
+		pub := p.channel(channel)
+		if gpub, ok := pub.(publisher.GroupPublisher); ok {
+			if err := p.publishGroup(ctx, topic, gpub, message, routed[channel]); err != nil {
+				errs = append(errs, err)
+			}
+		} else {
+			// the destination can not post the digests, so the alerts get
+			// their own threads
+			for _, alert := range routed[channel] {
+				if err := p.publishAlert(p.alertContext(ctx, alert), topic, pub, message, alert, ""); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		p.reportSuppressed(ctx, pub)
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// alertContext returns the context with the logger that identifies the alert.
+func (p *Processor) alertContext(ctx context.Context, alert *types.Alert) context.Context {
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("alert_fingerprint", alert.Fingerprint()),
+		zap.String("alert_thread_fingerprint", p.threadFingerprint(alert)),
+	)
+	return logutils.ContextWithLogger(ctx, l)
+}
+
+// screenAlert applies the drop rules, the silences and the inhibitions to the
+// alert.  It returns the labels to route the alert by, the thread fingerprint
+// of the inhibiting alert to fold the alert into (if any), and whether the
+// alert is to be published at all.
+func (p *Processor) screenAlert(
+	ctx context.Context,
+	topic string,
+	message *types.Message,
+	alert *types.Alert,
+) (map[string]string, string, bool) {
+	l := logutils.LoggerFromContext(ctx)
+
+	labels := router.Labels(message, alert)
+
+	if rule := p.filter.Match(labels, alert.Annotations); rule != nil {
+		if !p.filter.DryRun() {
+			l.Info("Dropped the alert according to drop rule",
+				zap.Any("alert", alert),
+				zap.String("drop_rule", rule.Name),
+				zap.Uint64("drop_rule_drops", rule.Drops()),
+			)
+			return nil, "", false
+		}
+		l.Info("Would have dropped the alert according to drop rule (dry-run)",
 			zap.Any("alert", alert),
+			zap.String("drop_rule", rule.Name),
+			zap.Uint64("drop_rule_drops", rule.Drops()),
 		)
 	}
 
-	// whatever the issues with DB we will try to publish to slack at least once
+	if p.inhibitor.IsSource(labels) {
+		p.trackActiveAlert(ctx, topic, labels, alert)
+	}
+
+	s, err := p.silencer.Match(ctx, labels)
+	if err != nil {
+		// better to be noisy than to miss an alert
+		l.Warn("Failed to check the silences, publishing the alert regardless",
+			zap.Error(err),
+		)
+	}
+	if s != nil {
+		l.Info("Silenced the alert",
+			zap.Any("alert", alert),
+			zap.String("silence_id", s.ID),
+		)
+		if p.silenceNotes {
+			for _, channel := range p.router.Route(labels) {
+				p.publishSilenceNote(ctx, topic, p.channel(channel.Name), alert, s)
+			}
+		}
+		return nil, "", false
+	}
+
+	foldInto := ""
+	if p.inhibitor.IsTarget(labels) {
+		active, err := p.db.GetActiveAlerts(ctx, topic)
+		if err != nil {
+			l.Warn("Failed to check the inhibitions, publishing the alert regardless",
+				zap.Error(err),
+			)
+		}
+		if inhibition := p.inhibitor.Match(labels, alert.ThreadFingerprint(nil, false), active); inhibition != nil {
+			if !inhibition.Fold {
+				l.Info("Inhibited the alert",
+					zap.Any("alert", alert),
+					zap.String("inhibiting_alert_fingerprint", inhibition.Source.Fingerprint),
+				)
+				return nil, "", false
+			}
+			l.Info("Folding the alert into the thread of inhibiting alert",
+				zap.String("inhibiting_alert_fingerprint", inhibition.Source.Fingerprint),
+			)
+			foldInto = inhibition.Source.ThreadFingerprint
+		}
+	}
+
+	return labels, foldInto, true
+}
+
+func (p *Processor) publishAlert(
+	ctx context.Context,
+	topic string,
+	pub publisher.Publisher,
+	message *types.Message,
+	alert *types.Alert,
+	foldThreadID string,
+) (err error) {
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	messageID := messageID(pub, alert)
+	threadID := threadID(pub, p.threadFingerprint(alert))
+	threadTS := ""
+	folded := false
+
+	// whatever the issues with DB we will try to publish at least once
 	shouldPublish := true
+	didLock := false
 	defer func() {
 		if shouldPublish {
-			_, err2 := p.slack.PublishMessage(ctx, slackThreadTS, alert)
+			messageTS, err2 := pub.PublishMessage(ctx, threadTS, message, alert)
 			if err2 == nil {
 				l.Warn("Emergency-published alert",
 					zap.Any("alert", alert),
 				)
+				_ = p.db.SetSlackMessageTS(ctx, topic, messageID, messageTS)
+			} else if didLock {
+				// let the retries proceed without waiting for the lease to end
+				_ = p.db.UnlockSlackMessage(ctx, topic, messageID)
 			}
 			err = errors.Join(err, err2)
 		}
 	}()
 
-	slackMessageTS, err := p.db.GetSlackMessageTS(ctx, topic, slackMessageID)
+	messageTS, err := p.db.GetSlackMessageTS(ctx, topic, messageID)
 	if err != nil {
 		return err
 	}
-	if len(slackMessageTS) > 0 {
+	if len(messageTS) > 0 {
 		// already published
 		shouldPublish = false
+		if foldThreadID == "" {
+			p.checkFlappingSettled(ctx, topic, pub, message, threadID)
+		}
 		return nil
 	}
-	didLock, err := p.db.LockSlackMessage(ctx, topic, slackMessageID)
+	didLock, err = p.db.LockSlackMessage(ctx, topic, messageID)
 	if !didLock && err == nil {
 		// another grafana's HA instance is about to publish
 		shouldPublish = false
-		return ErrAlreadyLocked
+		return types.Duplicate(ErrAlreadyLocked)
+	}
+
+	if foldThreadID != "" {
+		// the thread to fold into (e.g. the one of the inhibiting alert) may
+		// not exist at this destination, in which case the alert gets its
+		// own thread
+		threadTS, err = p.db.GetSlackThreadTS(ctx, topic, foldThreadID)
+		if err != nil {
+			return err
+		}
+		folded = threadTS != ""
 	}
+	var flapping *types.Flapping
+	if !folded {
+		prev, err := p.db.GetSlackThread(ctx, topic, threadID)
+		if err != nil {
+			return err
+		}
+		if prev != nil && prev.TS != "" {
+			threadTS = prev.TS
 
-	slackThreadTS, err = p.db.GetSlackThreadTS(ctx, topic, slackThreadID)
+			now := time.Now()
+			p.settleFlapping(ctx, topic, pub, threadID, prev, now)
+			flapping = p.trackFlapping(prev, alert, now)
+			if flapping.IsFlapping() && !prev.IsFlapping() {
+				l.Info("The alert started flapping",
+					zap.Int("flapping_transitions", len(flapping.Transitions)),
+				)
+			}
+		}
+	}
+	suppress := flapping.IsFlapping() && len(threadTS) > 0
+	if suppress {
+		flapping.Suppressed++
+	}
+	if flapping != nil {
+		// the flapping detection is best-effort
+		if err := p.db.SetSlackThreadFlapping(ctx, topic, threadID, flapping); err != nil {
+			l.Warn("Failed to keep the track of the flapping",
+				zap.Error(err),
+			)
+		}
+	}
+
+	if p.quietResolved && (!folded || p.digest) && len(threadTS) > 0 && alert.Status == types.AlertStatusResolved {
+		// the root message and the reaction tell it's resolved
+		messageTS = threadTS
+		shouldPublish = false
+		l.Info("Resolved alert quietly",
+			zap.Any("alert", alert),
+		)
+	} else if suppress {
+		// the root message tells it's flapping
+		messageTS = threadTS
+		shouldPublish = false
+		l.Info("Suppressed the alert since it is flapping",
+			zap.Any("alert", alert),
+		)
+	} else if !p.allowAlert(ctx, pub, alert) {
+		shouldPublish = false
+		l.Info("Suppressed the alert by the rate limits",
+			zap.Any("alert", alert),
+		)
+		if len(threadTS) == 0 {
+			// the lock stays (so that the duplicates get dropped), and the
+			// alert that is re-sent after the lease gets another chance
+			return nil
+		}
+		// the root message still tells the current state
+		messageTS = threadTS
+	} else {
+		// the emergency publish is only for the db failures before this
+		// point, the failed publish must not be repeated here (it is retried,
+		// if at all, by the publisher itself or by the redelivery)
+		shouldPublish = false
+		messageTS, err = pub.PublishMessage(ctx, threadTS, message, alert)
+		if err != nil {
+			if didLock {
+				// let the retries proceed without waiting for the lease to end
+				_ = p.db.UnlockSlackMessage(ctx, topic, messageID)
+			}
+			return err
+		}
+		l.Info("Published alert",
+			zap.Any("alert", alert),
+		)
+	}
+
+	// we published the alert, we can ignore errors here
+	_ = p.db.SetSlackMessageTS(ctx, topic, messageID, messageTS)
+
+	if folded {
+		// the thread (and its state) belongs to the inhibiting alert (or to
+		// the group)
+		return nil
+	}
+
+	if len(threadTS) == 0 {
+		threadTS = messageTS
+		// we published the alert, we can ignore errors here
+		_ = p.db.SetSlackThreadTS(ctx, topic, threadID, threadTS)
+	}
+
+	if len(threadTS) > 0 {
+		thread, err := p.db.UpdateSlackThread(ctx, topic, threadID, alert)
+		if err != nil {
+			// we published the alert, we still can flag the thread
+			thread = &types.Thread{Alert: alert}
+		}
+		thread.ID = threadID
+		thread.TS = threadTS
+		thread.Topic = topic
+		pub.UpdateThread(ctx, message, thread)
+	}
+
+	return nil
+}
+
+// publishGroup posts (or refreshes) the digest of the group of alerts at the
+// destination, and then posts the alerts themselves into its thread.  The
+// new digest thread is started if there is none yet, or if all alerts of the
+// previous one got resolved and the group fires again.
+func (p *Processor) publishGroup(
+	ctx context.Context,
+	topic string,
+	pub publisher.GroupPublisher,
+	message *types.Message,
+	alerts []*types.Alert,
+) error {
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	groupID := groupID(pub, message.GroupFingerprint())
+
+	firing := false
+	groupAlerts := make([]*types.GroupAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		groupAlerts = append(groupAlerts, types.NewGroupAlert(p.threadFingerprint(alert), alert))
+		firing = firing || alert.Status == types.AlertStatusFiring
+	}
+
+	group, err := p.db.GetSlackGroup(ctx, topic, groupID)
 	if err != nil {
 		return err
 	}
 
-	slackMessageTS, err = p.slack.PublishMessage(ctx, slackThreadTS, alert)
+	if group == nil || group.TS == "" || (firing && group.Resolved()) {
+		digestID := digestID(pub, message.GroupFingerprint(), alerts)
+		didLock, err := p.db.LockSlackMessage(ctx, topic, digestID)
+		if err != nil {
+			return err
+		}
+		if !didLock {
+			// another grafana's HA instance is about to publish
+			return types.Duplicate(ErrAlreadyLocked)
+		}
+
+		group = &types.Group{Labels: message.GroupLabels}
+		group.Merge(groupAlerts, time.Now())
+		group.TS, err = pub.PublishGroup(ctx, message, group)
+		if err != nil {
+			// let the retries proceed without waiting for the lease to end
+			_ = p.db.UnlockSlackMessage(ctx, topic, digestID)
+			return err
+		}
+		l.Info("Published group digest",
+			zap.Int("alerts", len(alerts)),
+		)
+
+		// we published the digest, we can ignore errors here (the alerts
+		// would get their own threads then)
+		_ = p.db.SetSlackMessageTS(ctx, topic, digestID, group.TS)
+		_ = p.db.StartSlackGroup(ctx, topic, groupID, group)
+	} else {
+		updated, err := p.db.UpdateSlackGroup(ctx, topic, groupID, groupAlerts)
+		if err != nil {
+			// we still can refresh the digest with what we know
+			group.Merge(groupAlerts, time.Now())
+		} else {
+			updated.TS = group.TS
+			group = updated
+		}
+		pub.UpdateGroup(ctx, message, group)
+	}
+
+	errs := []error{}
+	for _, alert := range alerts {
+		if err := p.publishAlert(p.alertContext(ctx, alert), topic, pub, message, alert, groupID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// trackActiveAlert keeps the track of the firing alerts that can inhibit the
+// others.  The failures are only logged (the inhibitions are best-effort).
+func (p *Processor) trackActiveAlert(
+	ctx context.Context,
+	topic string,
+	labels map[string]string,
+	alert *types.Alert,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	fingerprint := alert.ThreadFingerprint(nil, false)
+
+	var err error
+	if alert.Status == types.AlertStatusResolved {
+		err = p.db.DeleteActiveAlert(ctx, topic, fingerprint)
+	} else {
+		err = p.db.SetActiveAlert(ctx, topic, &types.ActiveAlert{
+			ExpireOn:          time.Now().Add(activeAlertExpiry),
+			Fingerprint:       fingerprint,
+			Labels:            labels,
+			ThreadFingerprint: p.threadFingerprint(alert),
+		})
+	}
 	if err != nil {
-		return err
+		l.Warn("Failed to keep the track of the inhibiting alert",
+			zap.Error(err),
+		)
 	}
-	shouldPublish = false
-	l.Info("Published alert",
-		zap.Any("alert", alert),
+}
+
+// publishSilenceNote posts the note about the silence into the thread of the
+// alert (once per silence and thread, and only if the thread exists).  The
+// failures are only logged, since the silenced alert is not to be published
+// anyway.
+func (p *Processor) publishSilenceNote(
+	ctx context.Context,
+	topic string,
+	pub publisher.Publisher,
+	alert *types.Alert,
+	s *types.Silence,
+) {
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+		zap.String("silence_id", s.ID),
 	)
+	ctx = logutils.ContextWithLogger(ctx, l)
 
-	if len(slackThreadTS) == 0 {
-		slackThreadTS = slackMessageTS
-		// we published to slack, we can ignore errors here
-		_ = p.db.SetSlackThreadTS(ctx, topic, slackThreadID, slackThreadTS)
+	threadFingerprint := p.threadFingerprint(alert)
+	threadTS, err := p.db.GetSlackThreadTS(ctx, topic, threadID(pub, threadFingerprint))
+	if err != nil || threadTS == "" {
+		return
 	}
 
-	if len(slackThreadTS) > 0 {
-		p.slack.UpdateReaction(ctx, slackThreadTS, alert)
+	noteID := "silence/" + pub.ID() + "/" + s.ID + "/" + threadFingerprint
+	if locked, err := p.db.LockSlackMessage(ctx, topic, noteID); !locked || err != nil {
+		return
 	}
 
-	return nil
+	text := fmt.Sprintf(":no_bell: Silenced by %s until %s",
+		s.CreatedBy, s.EndsAt.UTC().Format(time.RFC3339),
+	)
+	if s.Comment != "" {
+		text += ": " + s.Comment
+	}
+	noteTS, err := pub.PublishNote(ctx, threadTS, text)
+	if err != nil {
+		_ = p.db.UnlockSlackMessage(ctx, topic, noteID)
+		return
+	}
+	_ = p.db.SetSlackMessageTS(ctx, topic, noteID, noteTS)
 }
 
-func (p *Processor) slackThreadID(alert *types.Alert) string {
-	return "alert/" + p.slack.ChannelName() + "/" + alert.LabelsFingerprint()
+// allowAlert tells whether the rate limits let the alert into the channel.
+// The failures are only logged (better to be noisy than to miss an alert).
+func (p *Processor) allowAlert(
+	ctx context.Context,
+	pub publisher.Publisher,
+	alert *types.Alert,
+) bool {
+	if !p.limiter.Enabled() {
+		return true
+	}
+	allowed, err := p.limiter.Allow(ctx, pub.ID(), alert.Labels["alertname"])
+	if err != nil {
+		logutils.LoggerFromContext(ctx).Warn("Failed to check the rate limits, publishing the alert regardless",
+			zap.Error(err),
+		)
+		return true
+	}
+	return allowed
+}
+
+// reportSuppressed posts the count of the alerts that the rate limits did
+// not let into the channel (if it is time to report them).  The failures are
+// only logged.
+func (p *Processor) reportSuppressed(
+	ctx context.Context,
+	pub publisher.Publisher,
+) {
+	if !p.limiter.Enabled() {
+		return
+	}
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	count, err := p.limiter.Report(ctx, pub.ID())
+	if err != nil || count == 0 {
+		return
+	}
+	text := fmt.Sprintf(":hourglass_flowing_sand: %d more alerts suppressed by the rate limits", count)
+	if _, err := pub.PublishNote(ctx, "", text); err != nil {
+		l.Warn("Failed to report the alerts suppressed by the rate limits",
+			zap.Error(err),
+			zap.Int("suppressed_alerts", count),
+		)
+		return
+	}
+	l.Info("Reported the alerts suppressed by the rate limits",
+		zap.Int("suppressed_alerts", count),
+	)
+}
+
+// normalizeTimestamp converts the timestamps sent by prometheus into the
+// format used by grafana (RFC3339), leaving the unrecognised ones as-is.
+func normalizeTimestamp(ts string) string {
+	_timestamp, err := time.Parse("2006-01-02T15:04:05Z07:00", ts) // Grafana
+	if err != nil {
+		_timestamp, err = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", ts) // Prometheus
+	}
+	if err != nil {
+		return ts
+	}
+	return _timestamp.Format("2006-01-02T15:04:05Z07:00")
+}
+
+func (p *Processor) threadFingerprint(alert *types.Alert) string {
+	return alert.ThreadFingerprint(p.thread.IdentityLabels, p.thread.IncludeStartsAt)
+}
+
+// Drops returns how many alerts each of the drop rules has dropped (or would
+// have dropped, in dry-run mode) since the start.
+func (p *Processor) Drops() map[string]uint64 {
+	return p.filter.Drops()
+}
+
+// channel returns the publisher of the slack channel with the name.
+func (p *Processor) channel(name string) publisher.Publisher {
+	return p.publishers[publisher.SlackChannelID(name)]
+}
+
+func threadID(pub publisher.Publisher, threadFingerprint string) string {
+	return "alert/" + pub.ID() + "/" + threadFingerprint
+}
+
+func groupID(pub publisher.Publisher, groupFingerprint string) string {
+	return "group/" + pub.ID() + "/" + groupFingerprint
+}
+
+// digestID identifies the digest that starts the thread of the group (the
+// duplicates of the same notification share it).
+func digestID(pub publisher.Publisher, groupFingerprint string, alerts []*types.Alert) string {
+	fingerprints := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		fingerprints = append(fingerprints, alert.Fingerprint())
+	}
+	slices.Sort(fingerprints)
+
+	sum := fnv.New64a()
+	for _, fp := range fingerprints {
+		sum.Write([]byte(fp))
+	}
+	return fmt.Sprintf("digest/%s/%s/%016x", pub.ID(), groupFingerprint, sum.Sum64())
 }
 
-func (p *Processor) slackMessageID(alert *types.Alert) string {
-	return "message/" + p.slack.ChannelName() + "/" + alert.Fingerprint()
+func messageID(pub publisher.Publisher, alert *types.Alert) string {
+	return "message/" + pub.ID() + "/" + alert.Fingerprint()
 }
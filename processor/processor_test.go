@@ -0,0 +1,227 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+const testTopic = "topic"
+
+// fakePublisher only implements the core of publisher.Publisher (the way the
+// destinations other than slack would), and records what it was asked to do.
+type fakePublisher struct {
+	id string
+
+	mx       sync.Mutex
+	calls    int     // of PublishMessage
+	failures []error // returned by the next calls of PublishMessage
+	messages []fakeMessage
+	notes    []string
+	seq      int
+	updates  []*types.Thread
+}
+
+type fakeMessage struct {
+	alert    *types.Alert
+	threadTS string
+	ts       string
+}
+
+func (f *fakePublisher) ID() string {
+	return f.id
+}
+
+func (f *fakePublisher) Name() string {
+	return "#" + f.id
+}
+
+func (f *fakePublisher) PublishMessage(
+	_ context.Context, threadTS string, _ *types.Message, alert *types.Alert,
+) (string, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.calls++
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return "", err
+	}
+	f.seq++
+	ts := fmt.Sprintf("%d.0", f.seq)
+	f.messages = append(f.messages, fakeMessage{alert: alert.Clone(), threadTS: threadTS, ts: ts})
+	return ts, nil
+}
+
+func (f *fakePublisher) PublishNote(_ context.Context, _, text string) (string, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.seq++
+	f.notes = append(f.notes, text)
+	return fmt.Sprintf("%d.0", f.seq), nil
+}
+
+func (f *fakePublisher) PublishReminder(ctx context.Context, thread *types.Thread, _ bool) (string, error) {
+	return f.PublishNote(ctx, thread.TS, "reminder")
+}
+
+func (f *fakePublisher) UpdateThread(_ context.Context, _ *types.Message, thread *types.Thread) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	f.updates = append(f.updates, thread)
+}
+
+// newTestProcessor returns the processor with the in-memory db whose
+// destinations are the fakes.
+func newTestProcessor(t *testing.T, configure func(cfg *config.Config)) (*Processor, *fakePublisher) {
+	cfg := &config.Config{
+		Processor: config.Processor{
+			DBBackend:        "memory",
+			LockLease:        time.Minute,
+			MessageRetention: time.Hour,
+		},
+		Slack: config.Slack{
+			ChannelID:   "C0",
+			ChannelName: "alerts",
+		},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pub := &fakePublisher{id: p.channel("alerts").ID()}
+	p.publishers[pub.ID()] = pub
+	return p, pub
+}
+
+func testMessage(status string, names ...string) *types.Message {
+	m := &types.Message{
+		GroupKey:    "{}:{team=\"infra\"}",
+		GroupLabels: map[string]string{"team": "infra"},
+		Receiver:    "slack",
+		Status:      status,
+	}
+	for _, name := range names {
+		m.Alerts = append(m.Alerts, types.Alert{
+			Annotations: map[string]string{"summary": name + " is down"},
+			Labels:      map[string]string{"alertname": name, "team": "infra"},
+			StartsAt:    "2024-01-01T00:00:00Z",
+			Status:      status,
+		})
+	}
+	return m
+}
+
+func TestProcessGroupWithoutDigests(t *testing.T) {
+	p, pub := newTestProcessor(t, func(cfg *config.Config) {
+		cfg.Processor.Digest = true
+	})
+	ctx := context.Background()
+
+	if err := p.ProcessMessage(ctx, testTopic, testMessage(types.AlertStatusFiring, "A", "B")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.ProcessMessage(ctx, testTopic, testMessage(types.AlertStatusResolved, "A")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the destination can not post the digests, so the alerts get their own
+	// threads instead
+	want := []struct {
+		alertname string
+		threadTS  string
+	}{
+		{alertname: "A", threadTS: ""},
+		{alertname: "B", threadTS: ""},
+		{alertname: "A", threadTS: "1.0"},
+	}
+	if len(pub.messages) != len(want) {
+		t.Fatalf("want %d messages, got %d", len(want), len(pub.messages))
+	}
+	for idx, w := range want {
+		got := pub.messages[idx]
+		if got.alert.Labels["alertname"] != w.alertname || got.threadTS != w.threadTS {
+			t.Errorf("message %d: want %s in thread %q, got %s in thread %q", idx,
+				w.alertname, w.threadTS, got.alert.Labels["alertname"], got.threadTS,
+			)
+		}
+	}
+}
+
+func TestPublishAlertContinuesLegacyThread(t *testing.T) {
+	p, pub := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	// the threads tracked before there were other kinds of destinations are
+	// keyed by the bare name of the channel
+	message := testMessage(types.AlertStatusFiring, "A")
+	legacyID := "alert/alerts/" + p.threadFingerprint(&message.Alerts[0])
+	if err := p.db.SetSlackThreadTS(ctx, testTopic, legacyID, "9.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.ProcessMessage(ctx, testTopic, message); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.messages) != 1 || pub.messages[0].threadTS != "9.0" {
+		t.Fatalf("want the alert posted into the legacy thread, got %+v", pub.messages)
+	}
+}
+
+func TestPublishAlertDeduplicates(t *testing.T) {
+	p, pub := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	// alertmanager's HA instances send the same notifications
+	for range 2 {
+		if err := p.ProcessMessage(ctx, testTopic, testMessage(types.AlertStatusFiring, "A")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(pub.messages) != 1 {
+		t.Fatalf("want the duplicate dropped, got %d messages", len(pub.messages))
+	}
+
+	if err := p.ProcessMessage(ctx, testTopic, testMessage(types.AlertStatusResolved, "A")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pub.messages) != 2 || pub.messages[1].threadTS != pub.messages[0].ts {
+		t.Fatalf("want the resolution posted into the thread, got %+v", pub.messages)
+	}
+}
+
+func TestPublishAlertFailure(t *testing.T) {
+	p, pub := newTestProcessor(t, nil)
+	ctx := context.Background()
+
+	errPublish := types.Permanent(errors.New("channel_not_found"))
+	pub.failures = []error{errPublish}
+
+	err := p.ProcessMessage(ctx, testTopic, testMessage(types.AlertStatusFiring, "A"))
+	if !errors.Is(err, errPublish) {
+		t.Fatalf("want %v, got %v", errPublish, err)
+	}
+	if pub.calls != 1 {
+		t.Fatalf("want the failed publish not repeated, got %d calls", pub.calls)
+	}
+
+	// the lock is released, so the redelivery does not wait for the lease
+	if err := p.ProcessMessage(ctx, testTopic, testMessage(types.AlertStatusFiring, "A")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pub.calls != 2 || len(pub.messages) != 1 {
+		t.Fatalf("want the redelivery published, got %d calls and %d messages", pub.calls, len(pub.messages))
+	}
+}
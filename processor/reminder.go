@@ -0,0 +1,157 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"go.uber.org/zap"
+)
+
+// Tick runs the periodic chores: it settles the flapping alerts that went
+// quiet, reminds about the alerts that keep firing, escalates the ones that
+// nobody acknowledges, and reports the alerts suppressed by the rate limits
+// (in case no other alert came through to do those).  It is triggered by
+// EventBridge's schedule in lambda, and by the timer in standalone mode.
+func (p *Processor) Tick(ctx context.Context) error {
+	errs := []error{}
+	if p.flapping.Transitions > 0 || len(p.reminders) > 0 || p.escalator.Enabled() {
+		if err := p.tickThreads(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, pub := range p.publishers {
+		p.reportSuppressed(ctx, pub)
+	}
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// tickThreads settles the flapping alerts that have kept their status for
+// long enough, posts the reminders into the threads of the alerts that have
+// been firing for longer than the repeat interval of their severity since
+// they started firing (or since the previous reminder), and escalates the
+// ones that are due for the next step of their escalation policy.
+func (p *Processor) tickThreads(ctx context.Context) error {
+	threads, err := p.db.GetSlackThreads(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, thread := range threads {
+		if thread.IsFlapping() {
+			p.settleThread(ctx, thread, now)
+		}
+		if len(p.reminders) > 0 {
+			p.remindThread(ctx, thread, now)
+		}
+		if p.escalator.Enabled() {
+			p.escalateThread(ctx, thread, now)
+		}
+	}
+	return nil
+}
+
+// remindThread posts the reminder into the thread if it is due.  The
+// failures are only logged (the next tick retries).
+func (p *Processor) remindThread(ctx context.Context, thread *types.Thread, now time.Time) {
+	if thread.TS == "" || thread.Alert == nil || thread.Status() != types.AlertStatusFiring {
+		return
+	}
+	if thread.Resolved() || thread.IsFlapping() {
+		return
+	}
+	if a := thread.Action; a != nil && a.Type == types.ThreadActionSilence && a.SilencedUntil.After(now) {
+		return
+	}
+	reminder := p.reminderFor(thread.Alert)
+	if reminder == nil {
+		return
+	}
+	last := thread.StatusChangedAt
+	if thread.RemindedAt.After(last) {
+		last = thread.RemindedAt
+	}
+	if now.Sub(last) < reminder.RepeatInterval {
+		return
+	}
+	pub, known := p.publishers[threadDestination(thread.ID)]
+	if !known {
+		return // the channel is not configured anymore
+	}
+
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+		zap.String("slack_thread_id", thread.ID),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	reminded, err := p.db.SetSlackThreadReminded(ctx, thread.Topic, thread.ID, thread.RemindedAt, now)
+	if err != nil || !reminded {
+		// someone else is reminding
+		return
+	}
+	if _, err := pub.PublishReminder(ctx, thread, reminder.Mention); err != nil {
+		// let the next tick try again
+		_, _ = p.db.SetSlackThreadReminded(ctx, thread.Topic, thread.ID, now, thread.RemindedAt)
+		return
+	}
+	l.Info("Reminded about the alert that keeps firing",
+		zap.Duration("repeat_interval", reminder.RepeatInterval),
+	)
+}
+
+// settleThread settles the flapping alert of the thread if it is due, and
+// refreshes the root message of the thread.
+func (p *Processor) settleThread(ctx context.Context, thread *types.Thread, now time.Time) {
+	if thread.TS == "" || p.flapping.Transitions <= 0 {
+		return
+	}
+	pub, known := p.publishers[threadDestination(thread.ID)]
+	if !known {
+		return // the channel is not configured anymore
+	}
+
+	l := logutils.LoggerFromContext(ctx).With(
+		zap.String("destination", pub.ID()),
+		zap.String("slack_thread_id", thread.ID),
+	)
+	ctx = logutils.ContextWithLogger(ctx, l)
+
+	if p.settleFlapping(ctx, thread.Topic, pub, thread.ID, thread, now) {
+		pub.UpdateThread(ctx, nil, thread)
+	}
+}
+
+// reminderFor returns the reminder for the severity of the alert (falling
+// back to the one for any severity), or nil if there is none.
+func (p *Processor) reminderFor(alert *types.Alert) *config.Reminder {
+	var fallback *config.Reminder
+	for _, r := range p.reminders {
+		switch r.Severity {
+		case alert.Labels["severity"]:
+			return r
+		case "":
+			if fallback == nil {
+				fallback = r
+			}
+		}
+	}
+	return fallback
+}
+
+// threadDestination returns the destination that the thread (identified by
+// threadID) was published to.
+func threadDestination(threadID string) string {
+	parts := strings.SplitN(threadID, "/", 3)
+	if len(parts) != 3 {
+		return ""
+	}
+	return parts[1]
+}
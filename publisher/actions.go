@@ -0,0 +1,54 @@
+package publisher
+
+import (
+	"encoding/json"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/interaction"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+)
+
+const (
+	actionsBlockID = "thread_actions"
+)
+
+// newActionsAttachment returns the attachment with the buttons that let the
+// users acknowledge, silence, or resolve the alert of the thread (or nil if
+// there is nothing left to do with it).
+func (p *SlackChannel) newActionsAttachment(thread *types.Thread) *slack.Attachment {
+	if !p.interactive || thread == nil || thread.ID == "" || thread.Resolved() {
+		return nil
+	}
+
+	value, err := json.Marshal(types.ThreadRef{
+		Destination: p.ID(),
+		ID:          thread.ID,
+		Topic:       thread.Topic,
+	})
+	if err != nil {
+		return nil
+	}
+
+	button := func(actionID, text string) *slack.ButtonBlockElement {
+		return slack.NewButtonBlockElement(actionID, string(value),
+			slack.NewTextBlockObject(slack.PlainTextType, text, true, false),
+		)
+	}
+
+	buttons := []slack.BlockElement{}
+	if thread.Action == nil || thread.Action.Type != types.ThreadActionAcknowledge {
+		buttons = append(buttons, button(interaction.ActionAcknowledge, "Acknowledge").WithStyle(slack.StylePrimary))
+	}
+	buttons = append(buttons,
+		button(interaction.ActionSilence1h, "Silence 1h"),
+		button(interaction.ActionSilence4h, "Silence 4h"),
+		button(interaction.ActionSilence24h, "Silence 24h"),
+		button(interaction.ActionResolve, "Mark resolved").WithStyle(slack.StyleDanger),
+	)
+
+	return &slack.Attachment{
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewActionBlock(actionsBlockID, buttons...),
+		}},
+	}
+}
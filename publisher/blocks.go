@@ -0,0 +1,218 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+const (
+	LayoutAttachments = "attachments"
+	LayoutBlocks      = "blocks"
+
+	// https://api.slack.com/reference/block-kit/blocks
+	maxBlocks             = 50
+	maxActionElements     = 25
+	maxButtonText         = 75
+	maxButtonURL          = 3000
+	maxContextElements    = 10
+	maxContextElementText = 2000
+	maxHeaderText         = 150
+	maxImageAltText       = 2000
+	maxImageURL           = 3000
+	maxSectionFieldText   = 2000
+	maxSectionFields      = 10
+	maxSectionText        = 3000
+
+	ellipsis = "…"
+)
+
+var (
+	ErrBlocksEmptyHeader = errors.New("message header is empty")
+	ErrBlocksTooMany     = errors.New("message has too many blocks")
+)
+
+// newBlocksMessage lays the rendered alert out with the blocks (wrapped into
+// the attachment, so that the message still has colored side bar).
+func newBlocksMessage(
+	ctx context.Context,
+	r *Rendered,
+	data *TemplateData,
+	alert *types.Alert,
+) (slack.Attachment, error) {
+	title := strings.TrimSpace(r.Title)
+	if title == "" {
+		return slack.Attachment{}, ErrBlocksEmptyHeader
+	}
+
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject(slack.PlainTextType, truncate(title, maxHeaderText), true, false),
+		),
+	}
+
+	// text
+	for _, chunk := range split(strings.TrimSpace(r.Text), maxSectionText) {
+		if strings.TrimSpace(chunk) == "" {
+			continue
+		}
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, chunk, false, false), nil, nil,
+		))
+	}
+
+	// labels
+	fields := make([]*slack.TextBlockObject, 0, maxSectionFields)
+	for _, pair := range data.Labels.SortedPairs() {
+		if pair.Name == "alertname" { // it's in the header already
+			continue
+		}
+		if len(fields) == maxSectionFields {
+			blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+			fields = make([]*slack.TextBlockObject, 0, maxSectionFields)
+		}
+		fields = append(fields, slack.NewTextBlockObject(slack.MarkdownType,
+			truncate(fmt.Sprintf("*%s*\n`%s`", pair.Name, pair.Value), maxSectionFieldText), false, false,
+		))
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+
+	// state of the thread
+	if summary := threadSummary(data.Thread); len(summary) > 0 {
+		fields := make([]*slack.TextBlockObject, 0, len(summary))
+		for _, pair := range summary {
+			fields = append(fields, slack.NewTextBlockObject(slack.MarkdownType,
+				truncate(fmt.Sprintf("*%s*\n%s", pair.Name, pair.Value), maxSectionFieldText), false, false,
+			))
+		}
+		blocks = append(blocks, slack.NewDividerBlock(), slack.NewSectionBlock(nil, fields, nil))
+	}
+
+	// image (grafana's screenshot of the panel)
+	if len(data.ImageURL) > maxImageURL {
+		logutils.LoggerFromContext(ctx).Warn("Image url is too long for the blocks, leaving the image out",
+			zap.Int("image_url_length", len(data.ImageURL)),
+		)
+	} else if data.ImageURL != "" {
+		blocks = append(blocks, slack.NewImageBlock(data.ImageURL, truncate(title, maxImageAltText), "", nil))
+	}
+
+	// context
+	elements := []slack.MixedElement{}
+	if !data.StartsAt.IsZero() {
+		elements = append(elements, slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("Started at <!date^%d^{date_short_pretty} {time_secs}|%s>",
+				data.StartsAt.Unix(), data.StartsAt.Format("2006-01-02T15:04:05Z07:00"),
+			), false, false,
+		))
+	}
+	if footer := strings.TrimSpace(r.Footer); footer != "" {
+		elements = append(elements, slack.NewTextBlockObject(slack.MarkdownType,
+			truncate(footer, maxContextElementText), false, false,
+		))
+	}
+	if len(elements) > 0 {
+		if len(elements) > maxContextElements {
+			elements = elements[:maxContextElements]
+		}
+		blocks = append(blocks, slack.NewContextBlock("", elements...))
+	}
+
+	// links
+	buttons := []slack.BlockElement{}
+	for _, link := range links(alert) {
+		if len(buttons) == maxActionElements {
+			break
+		}
+		if len(link.url) > maxButtonURL {
+			continue
+		}
+		buttons = append(buttons, slack.NewButtonBlockElement(
+			link.actionID, "",
+			slack.NewTextBlockObject(slack.PlainTextType, truncate(link.text, maxButtonText), true, false),
+		).WithURL(link.url))
+	}
+	if len(buttons) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("links", buttons...))
+	}
+
+	if len(blocks) > maxBlocks {
+		return slack.Attachment{}, fmt.Errorf("%w: %d",
+			ErrBlocksTooMany, len(blocks),
+		)
+	}
+
+	return slack.Attachment{
+		Color:    r.Color,
+		Fallback: title,
+		Blocks:   slack.Blocks{BlockSet: blocks},
+	}, nil
+}
+
+type link struct {
+	actionID string
+	text     string
+	url      string
+}
+
+// links returns the urls that get their own buttons in the message.
+func links(alert *types.Alert) []link {
+	res := []link{}
+	if alert.GeneratorURL != "" {
+		res = append(res, link{actionID: "generator", text: "Source", url: alert.GeneratorURL})
+	}
+	if url := alert.Annotations["runbook_url"]; url != "" {
+		res = append(res, link{actionID: "runbook", text: "Runbook", url: url})
+	}
+	if url := alert.DashboardURL; url != "" {
+		res = append(res, link{actionID: "dashboard", text: "Dashboard", url: url})
+	} else if url := alert.Annotations["dashboard_url"]; url != "" {
+		res = append(res, link{actionID: "dashboard", text: "Dashboard", url: url})
+	}
+	if url := alert.PanelURL; url != "" {
+		res = append(res, link{actionID: "panel", text: "Panel", url: url})
+	}
+	if url := alert.SilenceURL; url != "" {
+		res = append(res, link{actionID: "silence", text: "Silence", url: url})
+	}
+	return res
+}
+
+// truncate cuts the text to the limit (in characters).
+func truncate(text string, limit int) string {
+	r := []rune(text)
+	if len(r) <= limit {
+		return text
+	}
+	return string(r[:limit-1]) + ellipsis
+}
+
+// split splits the text into the chunks that fit into the limit (preferably
+// on the line breaks).
+func split(text string, limit int) []string {
+	res := []string{}
+	for r := []rune(text); len(r) > 0; {
+		if len(r) <= limit {
+			res = append(res, string(r))
+			break
+		}
+		cut := limit
+		for i := limit - 1; i > limit/2; i-- {
+			if r[i] == '\n' {
+				cut = i + 1
+				break
+			}
+		}
+		res = append(res, string(r[:cut]))
+		r = r[cut:]
+	}
+	return res
+}
@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{name: "empty", text: "", limit: 3, want: ""},
+		{name: "shorter", text: "hi", limit: 5, want: "hi"},
+		{name: "exact", text: "hello", limit: 5, want: "hello"},
+		{name: "longer", text: "hello!", limit: 5, want: "hell" + ellipsis},
+		{name: "runes", text: "héllo wörld", limit: 6, want: "héllo" + ellipsis},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.text, tt.limit)
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+			if n := len([]rune(got)); n > tt.limit {
+				t.Errorf("want at most %d characters, got %d", tt.limit, n)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "empty", text: "", limit: 10, want: []string{}},
+		{name: "fits", text: "short", limit: 10, want: []string{"short"}},
+		{name: "exact", text: "abcdefghij", limit: 10, want: []string{"abcdefghij"}},
+		{name: "no line breaks", text: "abcdefghijklmno", limit: 10, want: []string{"abcdefghij", "klmno"}},
+		{name: "on line break", text: "abcdefg\nhijklmno", limit: 10, want: []string{"abcdefg\n", "hijklmno"}},
+		{
+			name:  "last line break",
+			text:  "abcdef\ng\nhijklmno",
+			limit: 10,
+			want:  []string{"abcdef\ng\n", "hijklmno"},
+		},
+		{
+			name:  "line break too early",
+			text:  "ab\ncdefghijklmn",
+			limit: 10,
+			want:  []string{"ab\ncdefghi", "jklmn"},
+		},
+		{
+			name:  "many chunks",
+			text:  "abcd\nefgh\nijkl\nmnop",
+			limit: 6,
+			want:  []string{"abcd\n", "efgh\n", "ijkl\n", "mnop"},
+		},
+		{
+			name:  "runes",
+			text:  strings.Repeat("é", 11),
+			limit: 10,
+			want:  []string{strings.Repeat("é", 10), "é"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := split(tt.text, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+			if joined := strings.Join(got, ""); joined != tt.text {
+				t.Errorf("want chunks to add up to %q, got %q", tt.text, joined)
+			}
+		})
+	}
+}
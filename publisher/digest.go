@@ -0,0 +1,91 @@
+package publisher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// maxDigestText is the limit of the text of the attachment (slack cuts
+	// longer ones off with "show more").
+	maxDigestText = 3000
+)
+
+// newDigestMessage lists the alerts of the group compactly (one line per
+// alert, the firing ones first).
+func newDigestMessage(message *types.Message, group *types.Group) slack.Attachment {
+	firing := group.Count(types.AlertStatusFiring)
+	resolved := group.Count(types.AlertStatusResolved)
+
+	status, color, count := types.AlertStatusFiring, "danger", firing
+	if firing == 0 {
+		status, color, count = types.AlertStatusResolved, "good", resolved
+	}
+
+	labels := group.Labels
+	if len(labels) == 0 && message != nil {
+		labels = message.GroupLabels
+	}
+	pairs := KV(labels).SortedPairs()
+	names := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		names = append(names, pair.Name+"="+pair.Value)
+	}
+	title := fmt.Sprintf("[%s:%d] %s",
+		strings.ToUpper(status), count, strings.Join(names, " "),
+	)
+
+	lines := []string{}
+	length := 0
+	alerts := group.Sorted()
+	for i, a := range alerts {
+		line := digestLine(a, labels)
+		// keep the room for the line that tells how many did not fit
+		if length+len(line)+1 > maxDigestText-32 {
+			lines = append(lines, fmt.Sprintf("%s and %d more", ellipsis, len(alerts)-i))
+			break
+		}
+		lines = append(lines, line)
+		length += len(line) + 1
+	}
+
+	return slack.Attachment{
+		Color:    color,
+		Fallback: title,
+		Footer:   fmt.Sprintf("%d firing, %d resolved", firing, resolved),
+		Text:     strings.Join(lines, "\n"),
+		Title:    truncate(title, maxHeaderText),
+	}
+}
+
+// digestLine is the line of the alert in the digest (the labels shared by
+// the whole group are omitted).
+func digestLine(a *types.GroupAlert, groupLabels map[string]string) string {
+	emoji := ":rotating_light:"
+	if a.Status == types.AlertStatusResolved {
+		emoji = ":white_check_mark:"
+	}
+
+	labels := make([]string, 0, len(a.Labels))
+	for _, pair := range KV(a.Labels).SortedPairs() {
+		if pair.Name == "alertname" {
+			continue
+		}
+		if _, shared := groupLabels[pair.Name]; shared {
+			continue
+		}
+		labels = append(labels, pair.Name+"="+pair.Value)
+	}
+
+	line := emoji + " *" + a.Labels["alertname"] + "*"
+	if len(labels) > 0 {
+		line += " `" + strings.Join(labels, " ") + "`"
+	}
+	if a.Summary != "" {
+		line += " " + truncate(a.Summary, maxButtonText*2)
+	}
+	return line
+}
@@ -0,0 +1,104 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+)
+
+const (
+	BroadcastChannel = "channel"
+	BroadcastHere    = "here"
+)
+
+var (
+	ErrMentionEmpty            = errors.New("mention must have users, user groups or broadcast")
+	ErrMentionInvalidBroadcast = errors.New("mention has invalid broadcast")
+	ErrMentionInvalidMatcher   = errors.New("mention has invalid matcher")
+)
+
+// Mentions pick whom to notify about the alert based on its labels.  All of
+// the rules that match contribute their mentions.
+type Mentions struct {
+	rules []*mention
+}
+
+type mention struct {
+	broadcast  string
+	matchers   matcher.Matchers
+	userGroups []string
+	users      []string
+}
+
+func NewMentions(cfg []*config.Mention) (*Mentions, error) {
+	m := &Mentions{}
+	for idx, c := range cfg {
+		p := fmt.Sprintf("mentions[%d]", idx)
+
+		switch c.Broadcast {
+		case "", BroadcastChannel, BroadcastHere:
+			// ok
+		default:
+			return nil, fmt.Errorf("%w: %s: %s",
+				ErrMentionInvalidBroadcast, p, c.Broadcast,
+			)
+		}
+		if len(c.Users)+len(c.UserGroups) == 0 && c.Broadcast == "" {
+			return nil, fmt.Errorf("%w: %s",
+				ErrMentionEmpty, p,
+			)
+		}
+		matchers, err := matcher.ParseAll(c.Matchers)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w",
+				ErrMentionInvalidMatcher, p, err,
+			)
+		}
+
+		m.rules = append(m.rules, &mention{
+			broadcast:  c.Broadcast,
+			matchers:   matchers,
+			userGroups: c.UserGroups,
+			users:      c.Users,
+		})
+	}
+	return m, nil
+}
+
+// Text returns the mentions for the alert with the labels formatted the way
+// slack expects them (or empty string if nobody is to be mentioned).
+func (m *Mentions) Text(labels map[string]string) string {
+	if m == nil {
+		return ""
+	}
+
+	var broadcast string
+	mentions := []string{}
+	add := func(s string) {
+		if !slices.Contains(mentions, s) {
+			mentions = append(mentions, s)
+		}
+	}
+	for _, r := range m.rules {
+		if !r.matchers.Matches(labels) {
+			continue
+		}
+		if r.broadcast == BroadcastChannel || (r.broadcast == BroadcastHere && broadcast == "") {
+			broadcast = r.broadcast
+		}
+		for _, u := range r.users {
+			add("<@" + u + ">")
+		}
+		for _, g := range r.userGroups {
+			add("<!subteam^" + g + ">")
+		}
+	}
+	if broadcast != "" {
+		mentions = append([]string{"<!" + broadcast + ">"}, mentions...)
+	}
+	return strings.Join(mentions, " ")
+}
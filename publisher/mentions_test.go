@@ -0,0 +1,95 @@
+package publisher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+)
+
+func TestMentionsText(t *testing.T) {
+	m, err := NewMentions([]*config.Mention{
+		{
+			Matchers: []string{`team="infra"`},
+			Users:    []string{"U1", "U2"},
+		},
+		{
+			Matchers:   []string{`severity="critical"`},
+			Broadcast:  BroadcastHere,
+			UserGroups: []string{"G1"},
+			Users:      []string{"U2"},
+		},
+		{
+			Matchers:  []string{`severity="critical"`, `env="prod"`},
+			Broadcast: BroadcastChannel,
+		},
+		{
+			// no matchers match all of the alerts
+			UserGroups: []string{"G0"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "catch-all only", labels: map[string]string{"team": "web"}, want: "<!subteam^G0>"},
+		{name: "users", labels: map[string]string{"team": "infra"}, want: "<@U1> <@U2> <!subteam^G0>"},
+		{
+			name:   "broadcast here",
+			labels: map[string]string{"severity": "critical"},
+			want:   "<!here> <@U2> <!subteam^G1> <!subteam^G0>",
+		},
+		{
+			name:   "deduplicated",
+			labels: map[string]string{"team": "infra", "severity": "critical"},
+			want:   "<!here> <@U1> <@U2> <!subteam^G1> <!subteam^G0>",
+		},
+		{
+			name:   "channel beats here",
+			labels: map[string]string{"severity": "critical", "env": "prod"},
+			want:   "<!channel> <@U2> <!subteam^G1> <!subteam^G0>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Text(tt.labels); got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if got := (*Mentions)(nil).Text(map[string]string{"team": "infra"}); got != "" {
+		t.Errorf("want no mentions without the rules, got %q", got)
+	}
+}
+
+func TestNewMentionsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		mention *config.Mention
+		errIs   error
+	}{
+		{name: "empty", mention: &config.Mention{Matchers: []string{`team="infra"`}}, errIs: ErrMentionEmpty},
+		{name: "invalid broadcast", mention: &config.Mention{Broadcast: "everyone"}, errIs: ErrMentionInvalidBroadcast},
+		{
+			name:    "invalid matcher",
+			mention: &config.Mention{Matchers: []string{`team`}, Users: []string{"U1"}},
+			errIs:   ErrMentionInvalidMatcher,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMentions([]*config.Mention{tt.mention})
+			if !errors.Is(err, tt.errIs) {
+				t.Errorf("want error %v, got %v", tt.errIs, err)
+			}
+		})
+	}
+}
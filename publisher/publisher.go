@@ -2,194 +2,80 @@ package publisher
 
 import (
 	"context"
-	"fmt"
-	"math"
-	"strconv"
-	"strings"
-	"time"
 
-	"github.com/flashbots/prometheus-sns-lambda-slack/config"
-	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
 	"github.com/flashbots/prometheus-sns-lambda-slack/types"
-	"github.com/slack-go/slack"
-	"go.uber.org/zap"
 )
 
-type SlackChannel struct {
-	channelID   string
-	channelName string
-	slack       *slack.Client
+// Publisher delivers the alerts to their destination (e.g. slack channel).
+// What only some of the destinations can do is behind the optional
+// interfaces (GroupPublisher, Permalinker, ReactionReader) that the callers
+// check for.
+type Publisher interface {
+	// ID identifies the destination.  It is a part of the keys under which
+	// the threads and the messages are tracked in the db, so it must be stable
+	// and unique across all destinations (the destinations other than slack
+	// must prefix it with their kind, e.g. "email:").
+	ID() string
+
+	// Name is the human-readable name of the destination (e.g. to refer to
+	// it in the other ones).
+	Name() string
+
+	// PublishMessage posts the alert (as a follow-up in the thread if the
+	// threadTS is not empty) and returns the timestamp of the new message.
+	// The message is the payload the alert came with (it may be nil).
+	// The errors are classified (see types.Kind), so that the callers can
+	// tell whether it is worth retrying.
+	PublishMessage(ctx context.Context, threadTS string, message *types.Message, alert *types.Alert) (string, error)
+
+	// PublishNote posts the plain text reply into the thread (or into the
+	// channel, if threadTS is empty) and returns the timestamp of the new
+	// message.
+	PublishNote(ctx context.Context, threadTS, text string) (string, error)
+
+	// PublishReminder posts the reminder about the alert of the thread that
+	// keeps firing (optionally mentioning the same people the first message
+	// of the thread did) and returns the timestamp of the new message.
+	PublishReminder(ctx context.Context, thread *types.Thread, mention bool) (string, error)
+
+	// UpdateThread flags the thread as firing or resolved in accordance
+	// with the status of its latest alert, and refreshes its root message so
+	// that it reflects the current state.
+	UpdateThread(ctx context.Context, message *types.Message, thread *types.Thread)
 }
 
-func NewSlackChannel(cfg *config.Config) *SlackChannel {
-	return &SlackChannel{
-		channelName: cfg.Slack.ChannelName,
-		channelID:   cfg.Slack.ChannelID,
+// GroupPublisher is the Publisher that can post the digests of the groups of
+// alerts (see config.Processor.Digest).  At the other destinations the
+// alerts get their own threads even in the digest mode.
+type GroupPublisher interface {
+	Publisher
 
-		slack: slack.New(cfg.Slack.Token),
-	}
-}
-
-func (p *SlackChannel) ChannelName() string {
-	return p.channelName
-}
-
-func (p *SlackChannel) newMessage(alert *types.Alert) slack.Attachment {
-	msg := slack.Attachment{}
-
-	if alert.Status == "firing" {
-		switch alert.Labels["severity"] {
-		case "critical":
-			msg.Color = "danger"
-		case "warning":
-			msg.Color = "warning"
-		default:
-			msg.Color = "good"
-		}
-	} else {
-		msg.Color = "good"
-	}
-
-	msg.Title = fmt.Sprintf("%s: %s",
-		strings.ToUpper(alert.Status),
-		alert.Labels["alertname"],
-	)
+	// PublishGroup posts the digest of the group of alerts (the root message
+	// of the group's thread) and returns the timestamp of the new message.
+	PublishGroup(ctx context.Context, message *types.Message, group *types.Group) (string, error)
 
-	if alertSeverity, ok := alert.Labels["severity"]; ok {
-		msg.Text += fmt.Sprintf("Severity: `%s`\n", alertSeverity)
-	}
-	if alertSummary, ok := alert.Annotations["summary"]; ok {
-		msg.Text += fmt.Sprintf("Summary: `%s`\n", alertSummary)
-	}
-	if alertDescription, ok := alert.Annotations["description"]; ok {
-		msg.Text += fmt.Sprintf("\n%s\n\n", alertDescription)
-	}
-	if alertMessage, ok := alert.Annotations["message"]; ok {
-		msg.Text += fmt.Sprintf("\n%s\n\n", alertMessage)
-	}
-	if len(alert.StartsAt) > 0 {
-		msg.Text += fmt.Sprintf("Started at: `%s`\n", alert.StartsAt)
-	}
-	if awsAccount, ok := alert.Labels["aws_account"]; ok {
-		msg.Text += fmt.Sprintf("AWS account: `%s`\n", awsAccount)
-	}
-	if cluster, ok := alert.Labels["cluster"]; ok {
-		msg.Text += fmt.Sprintf("Kubernetes cluster: `%s`\n", cluster)
-	}
-	if namespace, ok := alert.Labels["namespace"]; ok {
-		msg.Text += fmt.Sprintf("Kubernetes namespace: `%s`\n", namespace)
-	}
-
-	return msg
+	// UpdateGroup flags the digest of the group as firing or resolved, and
+	// refreshes it so that it reflects the current state of the group.
+	UpdateGroup(ctx context.Context, message *types.Message, group *types.Group)
 }
 
-func (p *SlackChannel) PublishMessage(
-	ctx context.Context,
-	slackThreadTS string,
-	alert *types.Alert,
-) (string, error) {
-	l := logutils.LoggerFromContext(ctx)
-
-	msg := p.newMessage(alert)
-	if len(slackThreadTS) > 0 {
-		if floatThreadTS, err := strconv.ParseFloat(slackThreadTS, 64); err == nil {
-			sec, dec := math.Modf(floatThreadTS)
-			timeSlackThreadTS := time.Unix(int64(sec), int64(dec*(1e9)))
-			msg.Footer = fmt.Sprintf(
-				"(follow-up to the alert published at %s)",
-				timeSlackThreadTS.Format("2006-01-02T15:04:05Z07:00"),
-			)
-		} else {
-			msg.Footer = "(follow-up)"
-		}
-	}
-
-	opts := []slack.MsgOption{
-		slack.MsgOptionAttachments(msg),
-	}
-	if len(slackThreadTS) > 0 {
-		opts = append(opts,
-			slack.MsgOptionTS(slackThreadTS),
-		)
-	}
+// Permalinker is the Publisher whose messages can be linked from the other
+// destinations.
+type Permalinker interface {
+	Publisher
 
-	_, msgTS, err := p.slack.PostMessage(p.channelName, opts...)
-	if err != nil {
-		l.Error("Error publishing message to slack",
-			zap.Error(err),
-			zap.String("slack_channel", p.channelName),
-			zap.String("slack_message_ts", msgTS),
-			zap.String("slack_thread_ts", slackThreadTS),
-		)
-		return "", err
-	}
-
-	return msgTS, nil
+	// Permalink returns the link to the message (e.g. to the root message of
+	// the thread).
+	Permalink(ctx context.Context, ts string) (string, error)
 }
 
-func (p *SlackChannel) UpdateReaction(
-	ctx context.Context,
-	slackThreadTS string,
-	alert *types.Alert,
-) {
-	l := logutils.LoggerFromContext(ctx)
-
-	var ra, rr string
-	if alert.Status == "firing" {
-		ra = "rotating_light"
-		rr = "white_check_mark"
-	} else {
-		ra = "white_check_mark"
-		rr = "rotating_light"
-	}
-
-	if err := func() error {
-		err := p.slack.AddReaction(ra, slack.ItemRef{
-			Channel:   p.channelID,
-			Timestamp: slackThreadTS,
-		})
-		if err == nil {
-			return nil
-		}
-		slackErr, isSlackErr := err.(slack.SlackErrorResponse)
-		if !isSlackErr {
-			return err
-		}
-		if slackErr.Err == "already_reacted" {
-			return nil
-		}
-		return err
-	}(); err != nil {
-		l.Error("Error adding reaction to slack",
-			zap.Error(err),
-			zap.String("slack_channel", p.channelName),
-			zap.String("slack_reaction", ra),
-			zap.String("slack_thread_ts", slackThreadTS),
-		)
-	}
+// ReactionReader is the Publisher whose messages can be reacted to (e.g.
+// with emojis).
+type ReactionReader interface {
+	Publisher
 
-	if err := func() error {
-		err := p.slack.RemoveReaction(rr, slack.ItemRef{
-			Channel:   p.channelID,
-			Timestamp: slackThreadTS,
-		})
-		if err == nil {
-			return nil
-		}
-		slackErr, isSlackErr := err.(slack.SlackErrorResponse)
-		if !isSlackErr {
-			return err
-		}
-		if slackErr.Err == "no_reaction" {
-			return nil
-		}
-		return err
-	}(); err != nil {
-		l.Error("Error removing reaction from slack",
-			zap.Error(err),
-			zap.String("slack_channel", p.channelName),
-			zap.String("slack_reaction", rr),
-			zap.String("slack_thread_ts", slackThreadTS),
-		)
-	}
+	// ReactedBy returns the user who reacted to the message (e.g. to the
+	// root message of the thread) with one of the reactions, or empty string
+	// if nobody did.
+	ReactedBy(ctx context.Context, ts string, reactions []string) (string, error)
 }
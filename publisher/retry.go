@@ -0,0 +1,190 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+const (
+	retryMaxAttempts    = 5
+	retryInitialBackoff = 250 * time.Millisecond
+	retryMaxBackoff     = 8 * time.Second
+
+	// retryDeadlineMargin is what we keep in reserve before the deadline of
+	// the context (so that the lambda has the time to wrap up)
+	retryDeadlineMargin = time.Second
+)
+
+// retriableSlackErrors are the errors returned by slack api that are worth
+// retrying.  All others (e.g. `channel_not_found`, `not_in_channel`,
+// `invalid_auth`) are considered permanent.
+//
+// See: https://api.slack.com/methods/chat.postMessage#errors
+var retriableSlackErrors = map[string]struct{}{
+	"fatal_error":         {},
+	"internal_error":      {},
+	"ratelimited":         {},
+	"request_timeout":     {},
+	"service_unavailable": {},
+}
+
+// nonIdempotentSlackMethods are the slack api methods that must not be
+// retried if the request might have reached slack (e.g. after a timeout, or
+// after slack's `internal_error`), since each successful call posts another
+// message.
+var nonIdempotentSlackMethods = map[string]struct{}{
+	"chat.postMessage": {},
+}
+
+// benignSlackErrors are the errors that the callers treat as success.
+var benignSlackErrors = map[string]struct{}{
+	"already_reacted": {},
+	"no_reaction":     {},
+}
+
+// isRetriable tells whether it makes sense to retry after the error.
+func isRetriable(err error) bool {
+	var rateLimitedErr *slack.RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return true
+	}
+
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		_, retriable := retriableSlackErrors[slackErr.Err]
+		return retriable
+	}
+
+	var statusCodeErr slack.StatusCodeError
+	if errors.As(err, &statusCodeErr) {
+		return statusCodeErr.Code == http.StatusTooManyRequests || statusCodeErr.Code >= 500
+	}
+
+	// network issues and alike
+	return true
+}
+
+// isRetriableUnprocessed tells whether the error means that slack has not
+// processed the request (it was rate-limited, or was never sent), so that
+// even the non-idempotent calls can be retried after it.
+func isRetriableUnprocessed(err error) bool {
+	var rateLimitedErr *slack.RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return true
+	}
+
+	var slackErr slack.SlackErrorResponse
+	if errors.As(err, &slackErr) {
+		return slackErr.Err == "ratelimited"
+	}
+
+	var statusCodeErr slack.StatusCodeError
+	if errors.As(err, &statusCodeErr) {
+		return statusCodeErr.Code == http.StatusTooManyRequests
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// withRetry calls slack api until it succeeds, fails permanently, runs out
+// of attempts, or until the deadline of the context is too close.  It backs
+// off exponentially (with jitter) and honours slack's `Retry-After`.  The
+// non-idempotent methods are only retried if slack has not processed the
+// request.  The returned errors are classified as either permanent or
+// transient.
+func withRetry(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	backoff := retryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := call(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// we are out of time (not slack), the redelivery might do better
+			l.Error("Slack api call was interrupted, not retrying",
+				zap.Error(err),
+				zap.Int("attempt", attempt),
+				zap.String("slack_method", method),
+			)
+			return types.Transient(err)
+		}
+		if !isRetriable(err) {
+			var slackErr slack.SlackErrorResponse
+			if errors.As(err, &slackErr) {
+				if _, benign := benignSlackErrors[slackErr.Err]; benign {
+					return err
+				}
+			}
+			l.Error("Slack api call failed permanently, not retrying",
+				zap.Error(err),
+				zap.Int("attempt", attempt),
+				zap.String("slack_method", method),
+			)
+			return types.Permanent(err)
+		}
+		if _, nonIdempotent := nonIdempotentSlackMethods[method]; nonIdempotent && !isRetriableUnprocessed(err) {
+			l.Error("Slack api call failed, not retrying since it might have been processed",
+				zap.Error(err),
+				zap.Int("attempt", attempt),
+				zap.String("slack_method", method),
+			)
+			return types.Transient(err)
+		}
+		if attempt == retryMaxAttempts {
+			l.Error("Slack api call failed, out of retry attempts",
+				zap.Error(err),
+				zap.Int("attempt", attempt),
+				zap.String("slack_method", method),
+			)
+			return types.Transient(err)
+		}
+
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		var rateLimitedErr *slack.RateLimitedError
+		if errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > delay {
+			delay = rateLimitedErr.RetryAfter
+		}
+		backoff = min(2*backoff, retryMaxBackoff)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay+retryDeadlineMargin {
+			l.Error("Slack api call failed, no time left to retry",
+				zap.Error(err),
+				zap.Int("attempt", attempt),
+				zap.String("slack_method", method),
+				zap.Duration("retry_delay", delay),
+				zap.Time("deadline", deadline),
+			)
+			return types.Transient(err)
+		}
+
+		l.Warn("Slack api call failed, retrying",
+			zap.Error(err),
+			zap.Int("attempt", attempt),
+			zap.String("slack_method", method),
+			zap.Duration("retry_delay", delay),
+		)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return types.Transient(errors.Join(err, ctx.Err()))
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+)
+
+func TestWithRetryGivesUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		want   error
+	}{
+		{
+			name:   "permanent slack error",
+			method: "reactions.add",
+			err:    slack.SlackErrorResponse{Err: "channel_not_found"},
+			want:   types.ErrPermanent,
+		},
+		{
+			name:   "post that might have been processed",
+			method: "chat.postMessage",
+			err:    slack.SlackErrorResponse{Err: "internal_error"},
+			want:   types.ErrTransient,
+		},
+		{
+			name:   "deadline",
+			method: "reactions.add",
+			err:    fmt.Errorf("post: %w", context.DeadlineExceeded),
+			want:   types.ErrTransient,
+		},
+		{
+			name:   "cancellation",
+			method: "chat.postMessage",
+			err:    context.Canceled,
+			want:   types.ErrTransient,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := withRetry(context.Background(), tt.method, func(context.Context) error {
+				attempts++
+				return tt.err
+			})
+			if attempts != 1 {
+				t.Errorf("want 1 attempt, got %d", attempts)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err.Error()) {
+				t.Errorf("want %v, got %v", tt.err, err)
+			}
+			if kind := types.Kind(err); kind != tt.want {
+				t.Errorf("want the error classified as %v, got %v", tt.want, kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,568 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/router"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+)
+
+var (
+	_ GroupPublisher = (*SlackChannel)(nil)
+	_ Permalinker    = (*SlackChannel)(nil)
+	_ ReactionReader = (*SlackChannel)(nil)
+)
+
+type SlackChannel struct {
+	channelID   string
+	channelName string
+	interactive bool
+	layout      string
+	mentions    *Mentions
+	slack       *slack.Client
+	templates   *Templates
+}
+
+func NewSlackChannel(
+	cfg *config.Config,
+	templates *Templates,
+	mentions *Mentions,
+	channelID string,
+	channelName string,
+) *SlackChannel {
+	return &SlackChannel{
+		channelName: channelName,
+		channelID:   channelID,
+		interactive: cfg.Slack.SigningSecret != "",
+		layout:      cfg.Slack.Layout,
+		mentions:    mentions,
+		templates:   templates,
+
+		slack: slack.New(cfg.Slack.Token),
+	}
+}
+
+// ID returns the name of the channel.
+func (p *SlackChannel) ID() string {
+	return SlackChannelID(p.channelName)
+}
+
+// SlackChannelID returns the ID of the destination of the slack channel with
+// the name.  It is the bare name of the channel, so that the threads and the
+// messages tracked before there were other kinds of destinations are still
+// found under their keys (the names of slack channels can not contain ':', so
+// they never collide with the IDs prefixed with the kind).
+func SlackChannelID(channelName string) string {
+	return channelName
+}
+
+// Name returns the name of the channel.
+func (p *SlackChannel) Name() string {
+	return "#" + p.channelName
+}
+
+// newMessage renders the alert into the message.  Unless the layout is set
+// to blocks, the second returned value is the same as the first one.
+// Otherwise, the second one is the legacy attachments-based fallback.
+func (p *SlackChannel) newMessage(
+	ctx context.Context,
+	slackThreadTS string,
+	thread *types.Thread,
+	message *types.Message,
+	alert *types.Alert,
+) (slack.Attachment, slack.Attachment) {
+	l := logutils.LoggerFromContext(ctx)
+
+	data := newTemplateData(slackThreadTS, thread, message, alert)
+	r, err := p.templates.Render(data)
+	if err != nil {
+		l.Error("Error rendering the message",
+			zap.Error(err),
+		)
+	}
+	if r == nil {
+		// we still want to publish _something_
+		r = &Rendered{
+			Color: "danger",
+			Title: fmt.Sprintf("%s: %s",
+				strings.ToUpper(alert.Status),
+				alert.Labels["alertname"],
+			),
+			Text: fmt.Sprintf("Failed to render the message: `%s`", err),
+		}
+	}
+
+	fallback := slack.Attachment{
+		Color:    r.Color,
+		Footer:   r.Footer,
+		ImageURL: data.ImageURL,
+		Text:     r.Text,
+		Title:    r.Title,
+	}
+	for _, f := range threadSummary(thread) {
+		fallback.Fields = append(fallback.Fields, slack.AttachmentField{
+			Title: f.Name,
+			Value: f.Value,
+			Short: true,
+		})
+	}
+	if p.layout != LayoutBlocks {
+		return fallback, fallback
+	}
+
+	msg, err := newBlocksMessage(ctx, r, data, alert)
+	if err != nil {
+		l.Warn("Error laying the message out with blocks, falling back to attachments",
+			zap.Error(err),
+		)
+		return fallback, fallback
+	}
+	return msg, fallback
+}
+
+func (p *SlackChannel) PublishMessage(
+	ctx context.Context,
+	slackThreadTS string,
+	message *types.Message,
+	alert *types.Alert,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	msg, fallback := p.newMessage(ctx, slackThreadTS, nil, message, alert)
+
+	// only the first message of the thread notifies anyone
+	mentions := ""
+	if len(slackThreadTS) == 0 {
+		mentions = p.mentions.Text(router.Labels(message, alert))
+	}
+
+	post := func(msg slack.Attachment) (string, error) {
+		opts := []slack.MsgOption{
+			slack.MsgOptionAttachments(msg),
+		}
+		if len(mentions) > 0 {
+			opts = append(opts,
+				slack.MsgOptionText(mentions, false),
+			)
+		}
+		if len(slackThreadTS) > 0 {
+			opts = append(opts,
+				slack.MsgOptionTS(slackThreadTS),
+			)
+		}
+		var msgTS string
+		err := withRetry(ctx, "chat.postMessage", func(ctx context.Context) (err error) {
+			_, msgTS, err = p.slack.PostMessageContext(ctx, p.channelName, opts...)
+			return err
+		})
+		return msgTS, err
+	}
+
+	msgTS, err := post(msg)
+	if isInvalidBlocksErr(err) {
+		l.Warn("Slack rejected the blocks, falling back to attachments",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+		)
+		msgTS, err = post(fallback)
+	}
+	if err != nil {
+		l.Error("Error publishing message to slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_message_ts", msgTS),
+			zap.String("slack_thread_ts", slackThreadTS),
+		)
+		return "", err
+	}
+
+	return msgTS, nil
+}
+
+// PublishNote posts the plain text reply into the thread (or into the
+// channel, if there is no thread).
+func (p *SlackChannel) PublishNote(
+	ctx context.Context,
+	slackThreadTS string,
+	text string,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+	}
+	if len(slackThreadTS) > 0 {
+		opts = append(opts,
+			slack.MsgOptionTS(slackThreadTS),
+		)
+	}
+
+	var msgTS string
+	err := withRetry(ctx, "chat.postMessage", func(ctx context.Context) (err error) {
+		_, msgTS, err = p.slack.PostMessageContext(ctx, p.channelName, opts...)
+		return err
+	})
+	if err != nil {
+		l.Error("Error publishing note to slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_thread_ts", slackThreadTS),
+		)
+		return "", err
+	}
+
+	return msgTS, nil
+}
+
+// PublishReminder posts the reminder into the thread of the alert that keeps
+// firing.
+func (p *SlackChannel) PublishReminder(
+	ctx context.Context,
+	thread *types.Thread,
+	mention bool,
+) (string, error) {
+	text := ":alarm_clock: Still firing"
+	if d := thread.Duration(time.Now()); d > 0 {
+		text += fmt.Sprintf(" for %s", d.Round(time.Minute))
+	}
+	if mention && thread.Alert != nil {
+		if mentions := p.mentions.Text(router.Labels(nil, thread.Alert)); len(mentions) > 0 {
+			text = mentions + " " + text
+		}
+	}
+	return p.PublishNote(ctx, thread.TS, text)
+}
+
+// Permalink returns the link to the message.
+func (p *SlackChannel) Permalink(
+	ctx context.Context,
+	ts string,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	var link string
+	err := withRetry(ctx, "chat.getPermalink", func(ctx context.Context) (err error) {
+		link, err = p.slack.GetPermalinkContext(ctx, &slack.PermalinkParameters{
+			Channel: p.channelID,
+			Ts:      ts,
+		})
+		return err
+	})
+	if err != nil {
+		l.Error("Error getting permalink from slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_message_ts", ts),
+		)
+		return "", err
+	}
+
+	return link, nil
+}
+
+// ReactedBy returns the first user who reacted to the message with one of the
+// reactions.
+func (p *SlackChannel) ReactedBy(
+	ctx context.Context,
+	ts string,
+	reactions []string,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	var items []slack.ItemReaction
+	err := withRetry(ctx, "reactions.get", func(ctx context.Context) (err error) {
+		items, err = p.slack.GetReactionsContext(ctx, slack.ItemRef{
+			Channel:   p.channelID,
+			Timestamp: ts,
+		}, slack.GetReactionsParameters{Full: true})
+		return err
+	})
+	if err != nil {
+		l.Error("Error getting reactions from slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_message_ts", ts),
+		)
+		return "", err
+	}
+
+	for _, item := range items {
+		if slices.Contains(reactions, item.Name) && len(item.Users) > 0 {
+			return item.Users[0], nil
+		}
+	}
+	return "", nil
+}
+
+// PublishGroup posts the digest of the group of alerts.
+func (p *SlackChannel) PublishGroup(
+	ctx context.Context,
+	message *types.Message,
+	group *types.Group,
+) (string, error) {
+	l := logutils.LoggerFromContext(ctx)
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionAttachments(newDigestMessage(message, group)),
+	}
+	if mentions := p.groupMentions(message); len(mentions) > 0 {
+		opts = append(opts,
+			slack.MsgOptionText(mentions, false),
+		)
+	}
+
+	var msgTS string
+	err := withRetry(ctx, "chat.postMessage", func(ctx context.Context) (err error) {
+		_, msgTS, err = p.slack.PostMessageContext(ctx, p.channelName, opts...)
+		return err
+	})
+	if err != nil {
+		l.Error("Error publishing group digest to slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+		)
+		return "", err
+	}
+
+	p.react(ctx, msgTS, !group.Resolved())
+
+	return msgTS, nil
+}
+
+// UpdateGroup re-renders the digest of the group so that it reflects the
+// current state of the alerts, and flags it accordingly.
+func (p *SlackChannel) UpdateGroup(
+	ctx context.Context,
+	message *types.Message,
+	group *types.Group,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionAttachments(newDigestMessage(message, group)),
+	}
+	// the update would wipe the mentions out otherwise
+	if mentions := p.groupMentions(message); len(mentions) > 0 {
+		opts = append(opts,
+			slack.MsgOptionText(mentions, false),
+		)
+	}
+
+	err := withRetry(ctx, "chat.update", func(ctx context.Context) error {
+		_, _, _, err := p.slack.UpdateMessageContext(ctx, p.channelID, group.TS, opts...)
+		return err
+	})
+	if err != nil {
+		l.Error("Error updating the group digest",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_thread_ts", group.TS),
+		)
+	}
+
+	p.react(ctx, group.TS, !group.Resolved())
+}
+
+// groupMentions returns the mentions for the labels that all alerts of the
+// message have in common.
+func (p *SlackChannel) groupMentions(message *types.Message) string {
+	if message == nil {
+		return ""
+	}
+	return p.mentions.Text(router.Labels(message, &types.Alert{
+		Labels: message.CommonLabels,
+		Status: message.Status,
+	}))
+}
+
+// UpdateThread re-renders the root message of the thread so that it reflects
+// the current state of the alert, and flags it with the emoji reaction that
+// corresponds to the status.
+func (p *SlackChannel) UpdateThread(
+	ctx context.Context,
+	message *types.Message,
+	thread *types.Thread,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	slackThreadTS := thread.TS
+	alert := thread.Alert
+	if alert == nil {
+		// the thread was started, but its alert was never recorded (e.g.
+		// due to db issues), so there is nothing to flag it with
+		l.Warn("Skipped updating the thread without the alert",
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_thread_ts", slackThreadTS),
+		)
+		return
+	}
+
+	// the state of the thread may be unknown (e.g. due to db issues), in
+	// which case there is nothing to refresh the root message with
+	if !thread.StartedAt.IsZero() {
+		if err := p.updateRootMessage(ctx, message, thread); err != nil {
+			l.Error("Error updating the root message of the thread",
+				zap.Error(err),
+				zap.String("slack_channel", p.channelName),
+				zap.String("slack_thread_ts", slackThreadTS),
+			)
+		}
+	}
+
+	if thread.IsFlapping() {
+		// flipping the reactions back and forth is just noise
+		p.addReaction(ctx, slackThreadTS, "repeat")
+		return
+	}
+	p.react(ctx, slackThreadTS, !thread.Resolved() && alert.Status == types.AlertStatusFiring)
+	if thread.Flapping != nil {
+		p.removeReaction(ctx, slackThreadTS, "repeat")
+	}
+}
+
+// react flags the root message of the thread with the emoji reaction that
+// corresponds to the status (removing the one of the opposite status).
+func (p *SlackChannel) react(
+	ctx context.Context,
+	slackThreadTS string,
+	firing bool,
+) {
+	if firing {
+		p.addReaction(ctx, slackThreadTS, "rotating_light")
+		p.removeReaction(ctx, slackThreadTS, "white_check_mark")
+	} else {
+		p.addReaction(ctx, slackThreadTS, "white_check_mark")
+		p.removeReaction(ctx, slackThreadTS, "rotating_light")
+	}
+}
+
+func (p *SlackChannel) addReaction(
+	ctx context.Context,
+	slackThreadTS string,
+	reaction string,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	if err := func() error {
+		err := withRetry(ctx, "reactions.add", func(ctx context.Context) error {
+			return p.slack.AddReactionContext(ctx, reaction, slack.ItemRef{
+				Channel:   p.channelID,
+				Timestamp: slackThreadTS,
+			})
+		})
+		if err == nil {
+			return nil
+		}
+		slackErr, isSlackErr := err.(slack.SlackErrorResponse)
+		if !isSlackErr {
+			return err
+		}
+		if slackErr.Err == "already_reacted" {
+			return nil
+		}
+		return err
+	}(); err != nil {
+		l.Error("Error adding reaction to slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_reaction", reaction),
+			zap.String("slack_thread_ts", slackThreadTS),
+		)
+	}
+}
+
+func (p *SlackChannel) removeReaction(
+	ctx context.Context,
+	slackThreadTS string,
+	reaction string,
+) {
+	l := logutils.LoggerFromContext(ctx)
+
+	if err := func() error {
+		err := withRetry(ctx, "reactions.remove", func(ctx context.Context) error {
+			return p.slack.RemoveReactionContext(ctx, reaction, slack.ItemRef{
+				Channel:   p.channelID,
+				Timestamp: slackThreadTS,
+			})
+		})
+		if err == nil {
+			return nil
+		}
+		slackErr, isSlackErr := err.(slack.SlackErrorResponse)
+		if !isSlackErr {
+			return err
+		}
+		if slackErr.Err == "no_reaction" {
+			return nil
+		}
+		return err
+	}(); err != nil {
+		l.Error("Error removing reaction from slack",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+			zap.String("slack_reaction", reaction),
+			zap.String("slack_thread_ts", slackThreadTS),
+		)
+	}
+}
+
+func isInvalidBlocksErr(err error) bool {
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return false
+	}
+	return slackErr.Err == "invalid_blocks" || slackErr.Err == "invalid_attachments"
+}
+
+func (p *SlackChannel) updateRootMessage(
+	ctx context.Context,
+	message *types.Message,
+	thread *types.Thread,
+) error {
+	l := logutils.LoggerFromContext(ctx)
+
+	msg, fallback := p.newMessage(ctx, "", thread, message, thread.Alert)
+
+	// the update would wipe the mentions out otherwise
+	mentions := p.mentions.Text(router.Labels(message, thread.Alert))
+
+	actions := p.newActionsAttachment(thread)
+
+	update := func(msg slack.Attachment) error {
+		attachments := []slack.Attachment{msg}
+		if actions != nil {
+			attachments = append(attachments, *actions)
+		}
+		opts := []slack.MsgOption{
+			slack.MsgOptionAttachments(attachments...),
+		}
+		if len(mentions) > 0 {
+			opts = append(opts,
+				slack.MsgOptionText(mentions, false),
+			)
+		}
+		return withRetry(ctx, "chat.update", func(ctx context.Context) error {
+			_, _, _, err := p.slack.UpdateMessageContext(ctx, p.channelID, thread.TS, opts...)
+			return err
+		})
+	}
+
+	err := update(msg)
+	if isInvalidBlocksErr(err) {
+		l.Warn("Slack rejected the blocks, falling back to attachments",
+			zap.Error(err),
+			zap.String("slack_channel", p.channelName),
+		)
+		err = update(fallback)
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package publisher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+// threadSummary returns the state of the thread as it is shown in the root
+// message of the thread.
+func threadSummary(thread *types.Thread) []Pair {
+	if thread == nil {
+		return nil
+	}
+	status := strings.ToUpper(thread.Status())
+	if thread.Status() != types.AlertStatusResolved && thread.Resolved() {
+		status = strings.ToUpper(types.AlertStatusResolved) + " (manually)"
+	}
+	if thread.IsFlapping() {
+		status += " (flapping)"
+	}
+	summary := []Pair{
+		{Name: "Status", Value: status},
+		{Name: "Duration", Value: thread.Duration(time.Now()).Round(time.Second).String()},
+		{Name: "Fired", Value: strconv.Itoa(thread.FiringCount)},
+		{Name: "Resolved", Value: strconv.Itoa(thread.ResolvedCount)},
+		{Name: "Last change", Value: thread.LastChangeAt.Format("2006-01-02T15:04:05Z07:00")},
+	}
+	if thread.IsFlapping() {
+		summary = append(summary, Pair{Name: "Flapping", Value: fmt.Sprintf("since %s: %d changes, %d updates suppressed",
+			thread.Flapping.Since.Format("2006-01-02T15:04:05Z07:00"), thread.Flapping.Changes, thread.Flapping.Suppressed,
+		)})
+	}
+	if action := actionSummary(thread.Action); action != "" {
+		summary = append(summary, Pair{Name: "Action", Value: action})
+	}
+	return summary
+}
+
+// actionSummary describes the latest action taken on the thread.
+func actionSummary(action *types.ThreadAction) string {
+	if action == nil {
+		return ""
+	}
+	at := action.At.Format("2006-01-02T15:04:05Z07:00")
+	switch action.Type {
+	case types.ThreadActionAcknowledge:
+		return fmt.Sprintf("Acknowledged by <@%s> at %s", action.UserID, at)
+	case types.ThreadActionResolve:
+		return fmt.Sprintf("Resolved by <@%s> at %s", action.UserID, at)
+	case types.ThreadActionSilence:
+		res := fmt.Sprintf("Silenced by <@%s> until %s",
+			action.UserID, action.SilencedUntil.Format("2006-01-02T15:04:05Z07:00"),
+		)
+		if action.SilenceURL != "" {
+			res += fmt.Sprintf(" (<%s|%s>)", action.SilenceURL, action.SilenceID)
+		}
+		return res
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,401 @@
+package publisher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+const (
+	defaultTemplateTitle = `{{ .Status | toUpper }}: {{ .Labels.alertname }}`
+
+	defaultTemplateText = `
+{{- with .Labels.severity }}Severity: ` + "`{{ . }}`" + `
+{{ end -}}
+{{- with .Annotations.summary }}Summary: ` + "`{{ . }}`" + `
+{{ end -}}
+{{- with .Annotations.description }}
+{{ . }}
+
+{{ end -}}
+{{- with .Annotations.message }}
+{{ . }}
+
+{{ end -}}
+{{- if not .StartsAt.IsZero }}Started at: ` + "`{{ .StartsAt | date \"2006-01-02T15:04:05Z07:00\" }}`" + `
+{{ end -}}
+{{- with .Labels.aws_account }}AWS account: ` + "`{{ . }}`" + `
+{{ end -}}
+{{- with .Labels.cluster }}Kubernetes cluster: ` + "`{{ . }}`" + `
+{{ end -}}
+{{- with .Labels.namespace }}Kubernetes namespace: ` + "`{{ . }}`" + `
+{{ end -}}`
+
+	defaultTemplateFooter = `
+{{- if .ThreadTS -}}
+	{{- if .ThreadStartedAt.IsZero -}}
+		(follow-up)
+	{{- else -}}
+		(follow-up to the alert published at {{ .ThreadStartedAt | date "2006-01-02T15:04:05Z07:00" }})
+	{{- end -}}
+{{- end -}}`
+
+	defaultTemplateColor = `
+{{- if eq .Status "firing" -}}
+	{{- if eq .Labels.severity "critical" -}}
+		danger
+	{{- else if eq .Labels.severity "warning" -}}
+		warning
+	{{- else -}}
+		good
+	{{- end -}}
+{{- else -}}
+	good
+{{- end -}}`
+)
+
+var (
+	ErrTemplateFailedToParse  = errors.New("failed to parse the template")
+	ErrTemplateFailedToRender = errors.New("failed to render the template")
+)
+
+// KV is a set of labels (or annotations) as it is exposed to the templates.
+// It mimics the one of alertmanager.
+type KV map[string]string
+
+// Pair is a key/value pair of KV.
+type Pair struct {
+	Name  string
+	Value string
+}
+
+// Names returns the sorted names of the labels.
+func (kv KV) Names() []string {
+	names := make([]string, 0, len(kv))
+	for k := range kv {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Values returns the values of the labels sorted by their names.
+func (kv KV) Values() []string {
+	values := make([]string, 0, len(kv))
+	for _, k := range kv.Names() {
+		values = append(values, kv[k])
+	}
+	return values
+}
+
+// SortedPairs returns the key/value pairs sorted by the name.
+func (kv KV) SortedPairs() []Pair {
+	pairs := make([]Pair, 0, len(kv))
+	for _, k := range kv.Names() {
+		pairs = append(pairs, Pair{Name: k, Value: kv[k]})
+	}
+	return pairs
+}
+
+// Remove returns the copy of the labels without the ones with the names.
+func (kv KV) Remove(names []string) KV {
+	res := make(KV, len(kv))
+	for k, v := range kv {
+		if !slices.Contains(names, k) {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// TemplateData is what the templates are rendered with.
+type TemplateData struct {
+	Annotations  KV
+	EndsAt       time.Time
+	Fingerprint  string // assigned by alertmanager
+	GeneratorURL string
+	Labels       KV
+	StartsAt     time.Time
+	Status       string
+
+	// grafana's extras
+
+	DashboardURL string
+	ImageURL     string
+	PanelURL     string
+	SilenceURL   string
+	Values       map[string]float64
+	ValueString  string
+
+	// the fields of the message the alert came with
+
+	CommonAnnotations KV
+	CommonLabels      KV
+	ExternalURL       string
+	GroupKey          string
+	GroupLabels       KV
+	Receiver          string
+	TruncatedAlerts   int
+
+	// ThreadTS is the timestamp of the root message of the thread (empty
+	// when the message is the root one).
+	ThreadTS        string
+	ThreadStartedAt time.Time
+
+	// Thread is the state of the thread (only set when the root message is
+	// being updated).
+	Thread *types.Thread
+}
+
+func newTemplateData(
+	threadTS string,
+	thread *types.Thread,
+	message *types.Message,
+	alert *types.Alert,
+) *TemplateData {
+	data := &TemplateData{
+		Annotations:  KV(alert.Annotations),
+		Fingerprint:  alert.UpstreamFingerprint,
+		GeneratorURL: alert.GeneratorURL,
+		Labels:       KV(alert.Labels),
+		Status:       alert.Status,
+
+		DashboardURL: alert.DashboardURL,
+		ImageURL:     alert.ImageURL,
+		PanelURL:     alert.PanelURL,
+		SilenceURL:   alert.SilenceURL,
+		Values:       alert.Values,
+		ValueString:  alert.ValueString,
+
+		Thread:   thread,
+		ThreadTS: threadTS,
+	}
+	if startsAt, err := time.Parse(time.RFC3339, alert.StartsAt); err == nil {
+		data.StartsAt = startsAt
+	}
+	if endsAt, err := time.Parse(time.RFC3339, alert.EndsAt); err == nil && endsAt.After(data.StartsAt) {
+		data.EndsAt = endsAt // alertmanager sends zero time for the firing ones
+	}
+	if message != nil {
+		data.CommonAnnotations = KV(message.CommonAnnotations)
+		data.CommonLabels = KV(message.CommonLabels)
+		data.ExternalURL = message.ExternalURL
+		data.GroupKey = message.GroupKey
+		data.GroupLabels = KV(message.GroupLabels)
+		data.Receiver = message.Receiver
+		data.TruncatedAlerts = message.TruncatedAlerts
+	}
+	if threadTS != "" {
+		data.ThreadStartedAt = parseSlackTS(threadTS)
+	}
+	return data
+}
+
+// parseSlackTS converts slack's message timestamp into time (or returns zero
+// time if the timestamp is malformed).
+func parseSlackTS(ts string) time.Time {
+	floatTS, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	sec, dec := math.Modf(floatTS)
+	return time.Unix(int64(sec), int64(dec*(1e9)))
+}
+
+// Rendered is the alert rendered with the templates.
+type Rendered struct {
+	Color  string
+	Footer string
+	Text   string
+	Title  string
+}
+
+// Templates render the alerts into messages.
+type Templates struct {
+	custom   *templateSet
+	fallback *templateSet
+}
+
+type templateSet struct {
+	color  *template.Template
+	footer *template.Template
+	text   *template.Template
+	title  *template.Template
+}
+
+// NewTemplates parses the user-supplied templates.  The ones that are not
+// supplied default to the built-in ones.
+func NewTemplates(cfg *config.Templates) (*Templates, error) {
+	fallback, err := newTemplateSet(&config.Templates{
+		Color:  defaultTemplateColor,
+		Footer: defaultTemplateFooter,
+		Text:   defaultTemplateText,
+		Title:  defaultTemplateTitle,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	custom, err := newTemplateSet(&config.Templates{
+		Color:  firstNonEmpty(cfg.Color, defaultTemplateColor),
+		Footer: firstNonEmpty(cfg.Footer, defaultTemplateFooter),
+		Text:   firstNonEmpty(cfg.Text, defaultTemplateText),
+		Title:  firstNonEmpty(cfg.Title, defaultTemplateTitle),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Templates{
+		custom:   custom,
+		fallback: fallback,
+	}, nil
+}
+
+func newTemplateSet(cfg *config.Templates) (*templateSet, error) {
+	parse := func(name, text string) (*template.Template, error) {
+		t, err := template.New(name).
+			Option("missingkey=zero").
+			Funcs(templateFuncs).
+			Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w",
+				ErrTemplateFailedToParse, name, err,
+			)
+		}
+		return t, nil
+	}
+
+	var (
+		ts  = &templateSet{}
+		err error
+	)
+	if ts.color, err = parse("color", cfg.Color); err != nil {
+		return nil, err
+	}
+	if ts.footer, err = parse("footer", cfg.Footer); err != nil {
+		return nil, err
+	}
+	if ts.text, err = parse("text", cfg.Text); err != nil {
+		return nil, err
+	}
+	if ts.title, err = parse("title", cfg.Title); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// Render renders the data with the user-supplied templates, and falls back
+// to the built-in ones if that fails (so that the alert is still delivered).
+func (t *Templates) Render(data *TemplateData) (*Rendered, error) {
+	res, err := t.custom.render(data)
+	if err == nil {
+		return res, nil
+	}
+	res, err2 := t.fallback.render(data)
+	if err2 != nil {
+		return nil, errors.Join(err, err2)
+	}
+	return res, err
+}
+
+func (ts *templateSet) render(data *TemplateData) (*Rendered, error) {
+	execute := func(t *template.Template) (string, error) {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("%w: %s: %w",
+				ErrTemplateFailedToRender, t.Name(), err,
+			)
+		}
+		return buf.String(), nil
+	}
+
+	var (
+		res = &Rendered{}
+		err error
+	)
+	if res.Color, err = execute(ts.color); err != nil {
+		return nil, err
+	}
+	res.Color = strings.TrimSpace(res.Color)
+	if res.Footer, err = execute(ts.footer); err != nil {
+		return nil, err
+	}
+	if res.Text, err = execute(ts.text); err != nil {
+		return nil, err
+	}
+	if res.Title, err = execute(ts.title); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// templateFuncs are the helpers available to the templates (the ones of
+// alertmanager plus the most useful ones from sprig).
+var templateFuncs = template.FuncMap{
+	"toUpper":   strings.ToUpper,
+	"toLower":   strings.ToLower,
+	"title":     title,
+	"trimSpace": strings.TrimSpace,
+	"join":      func(sep string, s []string) string { return strings.Join(s, sep) },
+	"split":     func(sep, s string) []string { return strings.Split(s, sep) },
+	"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"quote":     strconv.Quote,
+	"trunc":     trunc,
+
+	"match": regexp.MatchString,
+	"reReplaceAll": func(pattern, repl, text string) string {
+		return regexp.MustCompile(pattern).ReplaceAllString(text, repl)
+	},
+
+	"default":     func(def, value string) string { return firstNonEmpty(value, def) },
+	"stringSlice": func(s ...string) []string { return s },
+
+	"now":   time.Now,
+	"date":  func(layout string, t time.Time) string { return t.Format(layout) },
+	"since": time.Since,
+	"humanizeDuration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+func trunc(length int, s string) string {
+	r := []rune(s)
+	if len(r) <= length {
+		return s
+	}
+	return string(r[:length])
+}
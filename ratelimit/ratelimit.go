@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+)
+
+var (
+	ErrRateLimitInvalid      = errors.New("invalid rate limit")
+	ErrReportIntervalInvalid = errors.New("rate limits report interval must be positive")
+)
+
+// Limiter caps how fast the alerts get posted.  The state of the limits is
+// kept in the db, so that it is shared by the concurrent invocations.
+type Limiter struct {
+	db db.DB
+
+	alertname      config.RateLimit
+	channel        config.RateLimit
+	global         config.RateLimit
+	reportInterval time.Duration
+}
+
+type limit struct {
+	bucketID string
+	config.RateLimit
+}
+
+func New(cfg *config.RateLimits, d db.DB) (*Limiter, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	return &Limiter{
+		db: d,
+
+		alertname:      cfg.Alertname,
+		channel:        cfg.Channel,
+		global:         cfg.Global,
+		reportInterval: cfg.ReportInterval,
+	}, nil
+}
+
+// Validate checks that the rate limits are well-formed.
+func Validate(cfg *config.RateLimits) error {
+	enabled := false
+	for _, l := range []limit{
+		{bucketID: "alertname", RateLimit: cfg.Alertname},
+		{bucketID: "channel", RateLimit: cfg.Channel},
+		{bucketID: "global", RateLimit: cfg.Global},
+	} {
+		if l.Burst < 0 {
+			return fmt.Errorf("%w: %s: burst must not be negative",
+				ErrRateLimitInvalid, l.bucketID,
+			)
+		}
+		if l.Burst > 0 && l.Period <= 0 {
+			return fmt.Errorf("%w: %s: period must be positive",
+				ErrRateLimitInvalid, l.bucketID,
+			)
+		}
+		enabled = enabled || l.Burst > 0
+	}
+	if enabled && cfg.ReportInterval <= 0 {
+		return ErrReportIntervalInvalid
+	}
+	return nil
+}
+
+// Enabled tells whether any of the limits is set.
+func (l *Limiter) Enabled() bool {
+	return l.alertname.Burst > 0 || l.channel.Burst > 0 || l.global.Burst > 0
+}
+
+// Allow takes a token from each of the buckets that the alert falls into
+// (the most specific first, so that a noisy alert does not drain the budget
+// of the others).  If any of them is empty, the tokens taken from the others
+// are returned, the alert is counted as suppressed in the channel, and false
+// is returned.
+func (l *Limiter) Allow(ctx context.Context, channelID, alertname string) (bool, error) {
+	taken := []limit{}
+	for _, limit := range []limit{
+		{bucketID: "alertname/" + alertname, RateLimit: l.alertname},
+		{bucketID: "channel/" + channelID, RateLimit: l.channel},
+		{bucketID: "global", RateLimit: l.global},
+	} {
+		if limit.Burst == 0 {
+			continue
+		}
+		ok, err := l.db.TakeRateLimitToken(ctx, limit.bucketID, limit.Burst, limit.Period)
+		if err == nil && ok {
+			taken = append(taken, limit)
+			continue
+		}
+		errs := []error{err}
+		for _, t := range taken {
+			errs = append(errs, l.db.ReturnRateLimitToken(ctx, t.bucketID, t.Burst, t.Period))
+		}
+		if err == nil {
+			errs = append(errs, l.db.AddRateLimitSuppressed(ctx, channelID))
+		}
+		return false, errors.Join(errs...)
+	}
+	return true, nil
+}
+
+// Report returns (and resets) the count of the alerts suppressed on their
+// way into the channel if it is time to report them (or zero otherwise).
+func (l *Limiter) Report(ctx context.Context, channelID string) (int, error) {
+	return l.db.TakeRateLimitSuppressed(ctx, channelID, l.reportInterval)
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.RateLimits
+		errIs error
+	}{
+		{name: "disabled", cfg: config.RateLimits{}},
+		{
+			name: "valid",
+			cfg: config.RateLimits{
+				Channel:        config.RateLimit{Burst: 10, Period: time.Minute},
+				ReportInterval: time.Minute,
+			},
+		},
+		{
+			name:  "negative burst",
+			cfg:   config.RateLimits{Global: config.RateLimit{Burst: -1}},
+			errIs: ErrRateLimitInvalid,
+		},
+		{
+			name:  "no period",
+			cfg:   config.RateLimits{Alertname: config.RateLimit{Burst: 1}, ReportInterval: time.Minute},
+			errIs: ErrRateLimitInvalid,
+		},
+		{
+			name:  "no report interval",
+			cfg:   config.RateLimits{Global: config.RateLimit{Burst: 1, Period: time.Minute}},
+			errIs: ErrReportIntervalInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.cfg)
+			if tt.errIs == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+				t.Errorf("want error %v, got %v", tt.errIs, err)
+			}
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	ctx := context.Background()
+	d := db.NewMemory(&config.Processor{})
+	l, err := New(&config.RateLimits{
+		Alertname:      config.RateLimit{Burst: 1, Period: time.Hour},
+		Global:         config.RateLimit{Burst: 2, Period: time.Hour},
+		ReportInterval: time.Minute,
+	}, d)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		alertname string
+		want      bool
+	}{
+		{name: "first", alertname: "A", want: true},
+		{name: "alertname exhausted", alertname: "A", want: false},
+		{name: "other alertname", alertname: "B", want: true},
+		{name: "global exhausted", alertname: "C", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.Allow(ctx, "C1", tt.alertname)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("want allow %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// the token taken for the alertname denied by the global limit is back
+	ok, err := d.TakeRateLimitToken(ctx, "alertname/C", 1, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Errorf("want the alertname token returned when the global limit denies")
+	}
+}
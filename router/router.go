@@ -0,0 +1,166 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+// The pseudo-labels that expose the fields of the payload to the matchers.
+// The double underscores keep them apart from the real labels (prometheus
+// reserves such names for internal use).
+const (
+	LabelExternalURL = "__external_url__"
+	LabelGroupKey    = "__group_key__"
+	LabelOrgID       = "__org_id__"
+	LabelReceiver    = "__receiver__"
+	LabelStatus      = "__status__"
+)
+
+var (
+	ErrRouteChannelIncomplete = errors.New("route must have both channel name and channel id")
+	ErrRouteInvalidMatcher    = errors.New("route has invalid matcher")
+)
+
+type Channel struct {
+	ID   string
+	Name string
+}
+
+func (c Channel) sameAs(other Channel) bool {
+	return c.Name == other.Name
+}
+
+// Router picks the channels for the alerts by walking the routing tree the
+// same way alertmanager does: the alert goes down the first matching child
+// route (or several of them, if they have `continue` set) and ends up in
+// the deepest matching ones.  The root route matches everything.
+type Router struct {
+	channels []Channel
+	root     *route
+}
+
+type route struct {
+	channel          Channel
+	continueMatching bool
+	matchers         matcher.Matchers
+	routes           []*route
+}
+
+func New(cfg *config.Slack) (*Router, error) {
+	r := &Router{
+		root: &route{
+			channel: Channel{ID: cfg.ChannelID, Name: cfg.ChannelName},
+		},
+	}
+	r.channels = append(r.channels, r.root.channel)
+
+	routes, err := r.newRoutes(r.root, cfg.Routes, "routes")
+	if err != nil {
+		return nil, err
+	}
+	r.root.routes = routes
+
+	return r, nil
+}
+
+func (r *Router) newRoutes(parent *route, cfg []*config.Route, path string) ([]*route, error) {
+	res := make([]*route, 0, len(cfg))
+	for idx, c := range cfg {
+		p := fmt.Sprintf("%s[%d]", path, idx)
+
+		if (c.ChannelID == "") != (c.ChannelName == "") {
+			return nil, fmt.Errorf("%w: %s",
+				ErrRouteChannelIncomplete, p,
+			)
+		}
+		matchers, err := matcher.ParseAll(c.Matchers)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w",
+				ErrRouteInvalidMatcher, p, err,
+			)
+		}
+
+		rt := &route{
+			channel:          parent.channel,
+			continueMatching: c.Continue,
+			matchers:         matchers,
+		}
+		if c.ChannelName != "" {
+			rt.channel = Channel{ID: c.ChannelID, Name: c.ChannelName}
+			r.addChannel(rt.channel)
+		}
+
+		if rt.routes, err = r.newRoutes(rt, c.Routes, p+".routes"); err != nil {
+			return nil, err
+		}
+		res = append(res, rt)
+	}
+	return res, nil
+}
+
+func (r *Router) addChannel(channel Channel) {
+	if !slices.ContainsFunc(r.channels, channel.sameAs) {
+		r.channels = append(r.channels, channel)
+	}
+}
+
+// Channels returns all distinct channels the alerts can be routed to.
+func (r *Router) Channels() []Channel {
+	return r.channels
+}
+
+// Route returns the distinct channels the alert with the labels must be
+// published to.
+func (r *Router) Route(labels map[string]string) []Channel {
+	res := []Channel{}
+	for _, c := range r.root.match(labels) {
+		if !slices.ContainsFunc(res, c.sameAs) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Labels returns the labels of the alert complemented with the pseudo-labels
+// derived from the alert and the message it came with (the message may be
+// nil).
+func Labels(message *types.Message, alert *types.Alert) map[string]string {
+	res := make(map[string]string, len(alert.Labels)+5)
+	for k, v := range alert.Labels {
+		res[k] = v
+	}
+	res[LabelStatus] = alert.Status
+	if message != nil {
+		res[LabelExternalURL] = message.ExternalURL
+		res[LabelGroupKey] = message.GroupKey
+		res[LabelReceiver] = message.Receiver
+		if message.OrgID != 0 {
+			res[LabelOrgID] = strconv.FormatInt(message.OrgID, 10)
+		}
+	}
+	return res
+}
+
+func (rt *route) match(labels map[string]string) []Channel {
+	if !rt.matchers.Matches(labels) {
+		return nil
+	}
+	res := []Channel{}
+	for _, child := range rt.routes {
+		matched := child.match(labels)
+		res = append(res, matched...)
+		if len(matched) > 0 && !child.continueMatching {
+			break
+		}
+	}
+	if len(res) == 0 {
+		res = append(res, rt.channel)
+	}
+	return res
+}
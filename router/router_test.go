@@ -0,0 +1,149 @@
+package router
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+func TestRoute(t *testing.T) {
+	cfg := &config.Slack{
+		ChannelID:   "C0",
+		ChannelName: "alerts",
+		Routes: []*config.Route{
+			{
+				ChannelID:   "C1",
+				ChannelName: "infra",
+				Matchers:    []string{`team="infra"`},
+				Routes: []*config.Route{
+					{
+						ChannelID:   "C2",
+						ChannelName: "infra-critical",
+						Matchers:    []string{`severity="critical"`},
+					},
+					{
+						// inherits the channel of the parent
+						Matchers: []string{`severity="warning"`},
+					},
+				},
+			},
+			{
+				ChannelID:   "C3",
+				ChannelName: "audit",
+				Continue:    true,
+				Matchers:    []string{`audit="true"`},
+			},
+			{
+				ChannelID:   "C4",
+				ChannelName: "apps",
+				Matchers:    []string{`team=~"web|api"`},
+			},
+			{
+				ChannelID:   "C5",
+				ChannelName: "never",
+				Matchers:    []string{`team=~"web|api"`},
+			},
+		},
+	}
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   []string
+	}{
+		{name: "root", labels: map[string]string{"team": "data"}, want: []string{"alerts"}},
+		{name: "child", labels: map[string]string{"team": "infra"}, want: []string{"infra"}},
+		{name: "grandchild", labels: map[string]string{"team": "infra", "severity": "critical"}, want: []string{"infra-critical"}},
+		{name: "inherited", labels: map[string]string{"team": "infra", "severity": "warning"}, want: []string{"infra"}},
+		{name: "first match wins", labels: map[string]string{"team": "web"}, want: []string{"apps"}},
+		{name: "continue", labels: map[string]string{"team": "api", "audit": "true"}, want: []string{"audit", "apps"}},
+		{name: "continue only", labels: map[string]string{"audit": "true"}, want: []string{"audit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, c := range r.Route(tt.labels) {
+				got = append(got, c.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	channels := []string{}
+	for _, c := range r.Channels() {
+		channels = append(channels, c.Name)
+	}
+	if want := []string{"alerts", "infra", "infra-critical", "audit", "apps", "never"}; !slices.Equal(channels, want) {
+		t.Errorf("want channels %v, got %v", want, channels)
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		route *config.Route
+		errIs error
+	}{
+		{name: "channel without id", route: &config.Route{ChannelName: "x"}, errIs: ErrRouteChannelIncomplete},
+		{name: "channel without name", route: &config.Route{ChannelID: "C1"}, errIs: ErrRouteChannelIncomplete},
+		{name: "invalid matcher", route: &config.Route{Matchers: []string{`team`}}, errIs: ErrRouteInvalidMatcher},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&config.Slack{
+				ChannelID:   "C0",
+				ChannelName: "alerts",
+				Routes:      []*config.Route{tt.route},
+			})
+			if !errors.Is(err, tt.errIs) {
+				t.Errorf("want error %v, got %v", tt.errIs, err)
+			}
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	alert := &types.Alert{
+		Status: types.AlertStatusFiring,
+		Labels: map[string]string{"alertname": "HighLatency"},
+	}
+	message := &types.Message{
+		ExternalURL: "http://alertmanager",
+		GroupKey:    "{}:{alertname=\"HighLatency\"}",
+		OrgID:       1,
+		Receiver:    "slack",
+	}
+
+	got := Labels(message, alert)
+	want := map[string]string{
+		"alertname":      "HighLatency",
+		LabelExternalURL: "http://alertmanager",
+		LabelGroupKey:    "{}:{alertname=\"HighLatency\"}",
+		LabelOrgID:       "1",
+		LabelReceiver:    "slack",
+		LabelStatus:      types.AlertStatusFiring,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("want %s=%q, got %q", k, v, got[k])
+		}
+	}
+
+	if _, present := Labels(nil, alert)[LabelReceiver]; present {
+		t.Errorf("want no payload pseudo-labels without the message")
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/config"
+	"github.com/flashbots/prometheus-sns-lambda-slack/logutils"
+	"github.com/flashbots/prometheus-sns-lambda-slack/processor"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxMessageSize = 4 * 1024 * 1024
+)
+
+type Server struct {
+	cfg       *config.Server
+	log       *zap.Logger
+	processor *processor.Processor
+	server    *http.Server
+}
+
+func New(cfg *config.Config, p *processor.Processor) *Server {
+	s := &Server{
+		cfg:       &cfg.Server,
+		log:       zap.L(),
+		processor: p,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealthcheck)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("POST /alerts", s.handleAlerts)
+	mux.HandleFunc("POST /alerts/{topic}", s.handleAlerts)
+	mux.HandleFunc("POST /slack/interactions", s.handleSlackInteractions)
+
+	s.server = &http.Server{
+		Addr:              cfg.Server.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Run serves the requests until SIGINT or SIGTERM is received, and then
+// gracefully shuts the server down letting the in-flight requests finish.
+func (s *Server) Run() error {
+	l := s.log
+	defer l.Sync() //nolint:errcheck
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	failure := make(chan error, 1)
+	go func() {
+		l.Info("Starting the server",
+			zap.String("listen_address", s.cfg.ListenAddress),
+		)
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failure <- err
+		}
+		close(failure)
+	}()
+
+	if s.cfg.TickInterval > 0 {
+		go s.tick(ctx)
+	}
+
+	select {
+	case err := <-failure:
+		return err
+	case <-ctx.Done():
+	}
+
+	l.Info("Shutting down the server",
+		zap.Duration("shutdown_timeout", s.cfg.ShutdownTimeout),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		l.Error("Failed to gracefully shut the server down",
+			zap.Error(err),
+		)
+		return err
+	}
+	return <-failure
+}
+
+// tick runs the periodic chores of the processor until the context is done.
+func (s *Server) tick(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l := s.log.With(
+				zap.String("event_id", uuid.New().String()),
+			)
+			if err := s.processor.Tick(logutils.ContextWithLogger(ctx, l)); err != nil {
+				l.Error("Failed to run the periodic chores",
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+func (s *Server) handleHealthcheck(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleMetrics exposes the counters in prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	drops := s.processor.Drops()
+	rules := make([]string, 0, len(drops))
+	for rule := range drops {
+		rules = append(rules, rule)
+	}
+	slices.Sort(rules)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP prometheus_sns_lambda_slack_dropped_alerts_total The count of alerts matched by drop rules.")
+	fmt.Fprintln(w, "# TYPE prometheus_sns_lambda_slack_dropped_alerts_total counter")
+	for _, rule := range rules {
+		fmt.Fprintf(w, "prometheus_sns_lambda_slack_dropped_alerts_total{rule=%s} %d\n",
+			strconv.Quote(rule), drops[rule],
+		)
+	}
+}
+
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	topic := r.PathValue("topic")
+	if topic == "" {
+		topic = s.cfg.Topic
+	}
+
+	l := s.log.With(
+		zap.String("event_id", uuid.New().String()),
+		zap.String("topic", topic),
+	)
+	ctx := logutils.ContextWithLogger(r.Context(), l)
+
+	var m types.Message
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMessageSize)).Decode(&m); err != nil {
+		l.Error("Error un-marshalling message",
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// alertmanager retries on 5xx only, so we report with 4xx the failures
+	// that retrying would not fix
+	if err := s.processor.ProcessMessage(ctx, topic, &m); err != nil {
+		switch types.Kind(err) {
+		case types.ErrDuplicate:
+			// someone else is publishing it
+		case types.ErrMalformed, types.ErrPermanent:
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleSlackInteractions(w http.ResponseWriter, r *http.Request) {
+	l := s.log.With(
+		zap.String("event_id", uuid.New().String()),
+	)
+	ctx := logutils.ContextWithLogger(r.Context(), l)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		l.Error("Error reading slack interaction",
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.processor.HandleInteraction(ctx, r.Header, body); err != nil {
+		http.Error(w, err.Error(), processor.InteractionStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,112 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/flashbots/prometheus-sns-lambda-slack/db"
+	"github.com/flashbots/prometheus-sns-lambda-slack/matcher"
+	"github.com/flashbots/prometheus-sns-lambda-slack/types"
+)
+
+const (
+	// cacheTTL is for how long the silences are cached (so that we do not
+	// hit the db for every alert).
+	cacheTTL = 30 * time.Second
+)
+
+var (
+	ErrSilenceInvalidMatcher = errors.New("silence has invalid matcher")
+	ErrSilenceInvalidPeriod  = errors.New("silence must end after it starts")
+	ErrSilenceNoMatchers     = errors.New("silence must have at least one matcher")
+)
+
+// Silencer tells whether the alerts are silenced.
+type Silencer struct {
+	db db.DB
+
+	mx        sync.Mutex
+	fetchedAt time.Time
+	silences  []*silence
+}
+
+type silence struct {
+	*types.Silence
+	matchers matcher.Matchers
+}
+
+func New(d db.DB) *Silencer {
+	return &Silencer{
+		db: d,
+	}
+}
+
+// Validate checks that the silence is well-formed.
+func Validate(s *types.Silence) error {
+	if len(s.Matchers) == 0 {
+		return ErrSilenceNoMatchers
+	}
+	if _, err := matcher.ParseAll(s.Matchers); err != nil {
+		return fmt.Errorf("%w: %w",
+			ErrSilenceInvalidMatcher, err,
+		)
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return ErrSilenceInvalidPeriod
+	}
+	return nil
+}
+
+// Match returns the active silence that matches the alert with the labels
+// (or nil if there is none).
+func (s *Silencer) Match(ctx context.Context, labels map[string]string) (*types.Silence, error) {
+	silences, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, silence := range silences {
+		if silence.State(now) == types.SilenceStateActive && silence.matchers.Matches(labels) {
+			return silence.Silence, nil
+		}
+	}
+	return nil, nil
+}
+
+// Invalidate drops the cached silences (e.g. after adding a new one), so
+// that they are re-fetched from the db on the next match.
+func (s *Silencer) Invalidate() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.fetchedAt = time.Time{}
+}
+
+func (s *Silencer) get(ctx context.Context) ([]*silence, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if time.Since(s.fetchedAt) < cacheTTL {
+		return s.silences, nil
+	}
+
+	raw, err := s.db.GetSilences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	silences := make([]*silence, 0, len(raw))
+	for _, r := range raw {
+		matchers, err := matcher.ParseAll(r.Matchers)
+		if err != nil || len(matchers) == 0 {
+			continue // should not happen, since we validate them
+		}
+		silences = append(silences, &silence{Silence: r, matchers: matchers})
+	}
+
+	s.fetchedAt = time.Now()
+	s.silences = silences
+	return silences, nil
+}
@@ -0,0 +1,12 @@
+package types
+
+import "time"
+
+// ActiveAlert is the firing alert that is tracked so that it can inhibit the
+// others.
+type ActiveAlert struct {
+	ExpireOn          time.Time         `json:"expireOn"`
+	Fingerprint       string            `json:"fingerprint"`
+	Labels            map[string]string `json:"labels"`
+	ThreadFingerprint string            `json:"threadFingerprint"`
+}
@@ -3,62 +3,91 @@ package types
 import (
 	"fmt"
 	"hash/fnv"
+	"io"
+	"maps"
 	"slices"
 )
 
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
 type Alert struct {
-	Annotations map[string]string `json:"annotations"`
-	Labels      map[string]string `json:"labels"`
-	StartsAt    string            `json:"startsAt"`
-	Status      string            `json:"status"`
+	Annotations  map[string]string `json:"annotations"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL"`
+	Labels       map[string]string `json:"labels"`
+	StartsAt     string            `json:"startsAt"`
+	Status       string            `json:"status"`
+
+	// UpstreamFingerprint is the fingerprint assigned by alertmanager (not to
+	// be confused with Fingerprint that is computed by us).
+	UpstreamFingerprint string `json:"fingerprint,omitempty"`
+
+	// grafana
+
+	DashboardURL string             `json:"dashboardURL,omitempty"`
+	ImageURL     string             `json:"imageURL,omitempty"`
+	PanelURL     string             `json:"panelURL,omitempty"`
+	SilenceURL   string             `json:"silenceURL,omitempty"`
+	Values       map[string]float64 `json:"values,omitempty"`
+	ValueString  string             `json:"valueString,omitempty"`
+}
+
+// Clone returns the deep copy of the alert.
+func (a *Alert) Clone() *Alert {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	c.Annotations = maps.Clone(a.Annotations)
+	c.Labels = maps.Clone(a.Labels)
+	c.Values = maps.Clone(a.Values)
+	return &c
 }
 
-func (a Alert) LabelsFingerprint() string {
+// ThreadFingerprint identifies the thread the alert belongs to.  It is based
+// on the identity labels (or on all labels if there are none, preferring the
+// fingerprint assigned by alertmanager), and optionally on the time the
+// alert started at.
+func (a Alert) ThreadFingerprint(identityLabels []string, includeStartsAt bool) string {
 	sum := fnv.New64a()
 
-	sortedLabels := make([]string, 0, len(a.Labels))
-	for l := range a.Labels {
-		sortedLabels = append(sortedLabels, l)
-	}
-	slices.Sort(sortedLabels)
-	for _, k := range sortedLabels {
-		sum.Write([]byte(k))
-		sum.Write([]byte{255})
-		sum.Write([]byte(a.Labels[k]))
+	if len(identityLabels) == 0 && a.UpstreamFingerprint != "" {
+		sum.Write([]byte(a.UpstreamFingerprint))
 		sum.Write([]byte{255})
+	} else {
+		labels := a.Labels
+		if len(identityLabels) > 0 {
+			labels = make(map[string]string, len(identityLabels))
+			for _, l := range identityLabels {
+				labels[l] = a.Labels[l]
+			}
+		}
+		writeSorted(sum, labels)
 	}
 
-	sum.Write([]byte(a.StartsAt))
-	sum.Write([]byte{255})
+	if includeStartsAt {
+		sum.Write([]byte(a.StartsAt))
+		sum.Write([]byte{255})
+	}
 
 	return fmt.Sprintf("%016x", sum.Sum64())
 }
 
+// Fingerprint identifies the alert along with its annotations, start time
+// and status (that is, it tells the duplicates apart from the follow-ups).
 func (a Alert) Fingerprint() string {
 	sum := fnv.New64a()
 
-	sortedAnnotations := make([]string, 0, len(a.Annotations))
-	for l := range a.Labels {
-		sortedAnnotations = append(sortedAnnotations, l)
-	}
-	slices.Sort(sortedAnnotations)
-	for _, k := range sortedAnnotations {
-		sum.Write([]byte(k))
-		sum.Write([]byte{255})
-		sum.Write([]byte(a.Annotations[k]))
-		sum.Write([]byte{255})
-	}
+	writeSorted(sum, a.Annotations)
 
-	sortedLabels := make([]string, 0, len(a.Labels))
-	for l := range a.Labels {
-		sortedLabels = append(sortedLabels, l)
-	}
-	slices.Sort(sortedLabels)
-	for _, k := range sortedLabels {
-		sum.Write([]byte(k))
-		sum.Write([]byte{255})
-		sum.Write([]byte(a.Labels[k]))
+	if a.UpstreamFingerprint != "" {
+		sum.Write([]byte(a.UpstreamFingerprint))
 		sum.Write([]byte{255})
+	} else {
+		writeSorted(sum, a.Labels)
 	}
 
 	sum.Write([]byte(a.StartsAt))
@@ -68,3 +97,17 @@ func (a Alert) Fingerprint() string {
 
 	return fmt.Sprintf("%016x", sum.Sum64())
 }
+
+func writeSorted(w io.Writer, kv map[string]string) {
+	keys := make([]string, 0, len(kv))
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		w.Write([]byte(k))
+		w.Write([]byte{255})
+		w.Write([]byte(kv[k]))
+		w.Write([]byte{255})
+	}
+}
@@ -0,0 +1,140 @@
+package types
+
+import (
+	"errors"
+)
+
+// The kinds of processing errors.  They tell the event sources whether
+// delivering the same event again makes any sense.
+var (
+	// ErrDuplicate means that the event is (or is about to be) processed by
+	// someone else.  It is not a failure.
+	ErrDuplicate = errors.New("duplicate")
+
+	// ErrMalformed means that the event can not be decoded.
+	ErrMalformed = errors.New("malformed input")
+
+	// ErrPermanent means that the processing failed, and would fail again
+	// (e.g. slack's `channel_not_found`).
+	ErrPermanent = errors.New("permanent failure")
+
+	// ErrTransient means that the processing failed, but a retry might
+	// succeed (e.g. throttling, network issues).
+	ErrTransient = errors.New("transient failure")
+)
+
+// kindPriority orders the kinds by precedence, so that the kind of the
+// joined errors is the kind of the most severe of them (a batch with at
+// least one transient failure is worth retrying).
+var kindPriority = []error{
+	ErrTransient,
+	ErrPermanent,
+	ErrMalformed,
+	ErrDuplicate,
+}
+
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func classify(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	if isClassified(err) {
+		// the innermost classification is the most precise one
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+func isClassified(err error) bool {
+	var c *classifiedError
+	if errors.As(err, &c) {
+		return true
+	}
+	for _, kind := range kindPriority {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// Duplicate marks the error as ErrDuplicate (unless it is already classified).
+func Duplicate(err error) error {
+	return classify(ErrDuplicate, err)
+}
+
+// Malformed marks the error as ErrMalformed (unless it is already classified).
+func Malformed(err error) error {
+	return classify(ErrMalformed, err)
+}
+
+// Permanent marks the error as ErrPermanent (unless it is already classified).
+func Permanent(err error) error {
+	return classify(ErrPermanent, err)
+}
+
+// Transient marks the error as ErrTransient (unless it is already classified).
+func Transient(err error) error {
+	return classify(ErrTransient, err)
+}
+
+// Kind returns the kind of the error (one of ErrDuplicate, ErrMalformed,
+// ErrPermanent, ErrTransient), or nil if there is no error.  The errors that
+// are not classified are considered transient.  The kind of the joined
+// errors is the kind of the most severe of them.
+func Kind(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, kind := range kindPriority {
+		if err == kind {
+			return kind
+		}
+	}
+
+	switch e := err.(type) {
+	case *classifiedError:
+		return e.kind
+	case interface{ Unwrap() []error }:
+		res := error(nil)
+		for _, err := range e.Unwrap() {
+			res = moreSevere(res, Kind(err))
+		}
+		return res
+	case interface{ Unwrap() error }:
+		return Kind(e.Unwrap())
+	default:
+		return ErrTransient
+	}
+}
+
+// IsRetriable tells whether delivering the event again might help.
+func IsRetriable(err error) bool {
+	return Kind(err) == ErrTransient
+}
+
+func moreSevere(a, b error) error {
+	for _, kind := range kindPriority {
+		if a == kind || b == kind {
+			return kind
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKind(t *testing.T) {
+	plain := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		err       error
+		want      error
+		retriable bool
+	}{
+		{name: "nil", err: nil, want: nil},
+		{name: "unclassified", err: plain, want: ErrTransient, retriable: true},
+		{name: "sentinel", err: ErrPermanent, want: ErrPermanent},
+		{name: "duplicate", err: Duplicate(plain), want: ErrDuplicate},
+		{name: "malformed", err: Malformed(plain), want: ErrMalformed},
+		{name: "permanent", err: Permanent(plain), want: ErrPermanent},
+		{name: "transient", err: Transient(plain), want: ErrTransient, retriable: true},
+		{name: "wrapped", err: fmt.Errorf("processing: %w", Permanent(plain)), want: ErrPermanent},
+		{name: "wrapped sentinel", err: fmt.Errorf("%w: bad json", ErrMalformed), want: ErrMalformed},
+		{name: "innermost wins", err: Transient(fmt.Errorf("retry: %w", Permanent(plain))), want: ErrPermanent},
+		{name: "sentinel not reclassified", err: Transient(ErrDuplicate), want: ErrDuplicate},
+		{
+			name:      "joined takes most severe",
+			err:       errors.Join(Duplicate(plain), Transient(plain), Permanent(plain)),
+			want:      ErrTransient,
+			retriable: true,
+		},
+		{name: "joined without transient", err: errors.Join(Duplicate(plain), Malformed(plain)), want: ErrMalformed},
+		{name: "joined with unclassified", err: errors.Join(Permanent(plain), plain), want: ErrTransient, retriable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Kind(tt.err); got != tt.want {
+				t.Errorf("want kind %v, got %v", tt.want, got)
+			}
+			if got := IsRetriable(tt.err); got != tt.retriable {
+				t.Errorf("want retriable %v, got %v", tt.retriable, got)
+			}
+		})
+	}
+}
+
+func TestClassifiedError(t *testing.T) {
+	plain := errors.New("boom")
+	err := Permanent(plain)
+
+	if !errors.Is(err, ErrPermanent) {
+		t.Errorf("want %v to be %v", err, ErrPermanent)
+	}
+	if errors.Is(err, ErrTransient) {
+		t.Errorf("want %v not to be %v", err, ErrTransient)
+	}
+	if !errors.Is(err, plain) {
+		t.Errorf("want %v to unwrap to %v", err, plain)
+	}
+	if want := "permanent failure: boom"; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+	if Permanent(nil) != nil {
+		t.Errorf("want nil error to stay nil")
+	}
+}
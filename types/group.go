@@ -0,0 +1,96 @@
+package types
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Group is the state of the digest thread of alertmanager's group of alerts.
+type Group struct {
+	TS string
+
+	// Alerts are the alerts of the group (by their thread fingerprints).
+	Alerts map[string]*GroupAlert
+	Labels map[string]string
+
+	LastChangeAt time.Time
+	StartedAt    time.Time
+}
+
+// GroupAlert is how the alert is listed in the digest of its group.
+type GroupAlert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	StartsAt    string            `json:"startsAt"`
+	Status      string            `json:"status"`
+	Summary     string            `json:"summary,omitempty"`
+}
+
+func NewGroupAlert(fingerprint string, alert *Alert) *GroupAlert {
+	summary := alert.Annotations["summary"]
+	if summary == "" {
+		summary = alert.Annotations["description"]
+	}
+	return &GroupAlert{
+		Fingerprint: fingerprint,
+		Labels:      maps.Clone(alert.Labels),
+		StartsAt:    alert.StartsAt,
+		Status:      alert.Status,
+		Summary:     summary,
+	}
+}
+
+// Merge records the latest state of the alerts.
+func (g *Group) Merge(alerts []*GroupAlert, now time.Time) {
+	if g.Alerts == nil {
+		g.Alerts = make(map[string]*GroupAlert, len(alerts))
+	}
+	for _, a := range alerts {
+		g.Alerts[a.Fingerprint] = a
+	}
+	g.LastChangeAt = now
+	if g.StartedAt.IsZero() {
+		g.StartedAt = now
+	}
+}
+
+// Count returns how many alerts of the group have the status.
+func (g *Group) Count(status string) int {
+	res := 0
+	for _, a := range g.Alerts {
+		if a.Status == status {
+			res++
+		}
+	}
+	return res
+}
+
+// Resolved tells whether all alerts of the group are resolved.
+func (g *Group) Resolved() bool {
+	return len(g.Alerts) > 0 && g.Count(AlertStatusFiring) == 0
+}
+
+// Sorted returns the alerts of the group, the firing ones first.
+func (g *Group) Sorted() []*GroupAlert {
+	res := make([]*GroupAlert, 0, len(g.Alerts))
+	for _, a := range g.Alerts {
+		res = append(res, a)
+	}
+	slices.SortFunc(res, func(a, b *GroupAlert) int {
+		if a.Status != b.Status {
+			if a.Status == AlertStatusFiring {
+				return -1
+			}
+			if b.Status == AlertStatusFiring {
+				return 1
+			}
+		}
+		if c := strings.Compare(a.Labels["alertname"], b.Labels["alertname"]); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+	return res
+}
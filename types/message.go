@@ -1,8 +1,48 @@
 package types
 
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// Message is the webhook payload of alertmanager (version 4) or of grafana's
+// unified alerting (which extends it).
+//
+// See: https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+// See: https://grafana.com/docs/grafana/latest/alerting/configure-notifications/manage-contact-points/integrations/webhook-notifier/
 type Message struct {
 	Alerts            []Alert           `json:"alerts"`
 	CommonAnnotations map[string]string `json:"commonAnnotations"`
 	CommonLabels      map[string]string `json:"commonLabels"`
+	ExternalURL       string            `json:"externalURL"`
+	GroupKey          string            `json:"groupKey"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	Receiver          string            `json:"receiver"`
 	Status            string            `json:"status"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Version           string            `json:"version"`
+
+	// grafana
+
+	Message string `json:"message,omitempty"`
+	OrgID   int64  `json:"orgId,omitempty"`
+	State   string `json:"state,omitempty"`
+	Title   string `json:"title,omitempty"`
+}
+
+// GroupFingerprint identifies alertmanager's group the alerts of the message
+// belong to (by its key, or by the labels of the group if there is none).
+func (m *Message) GroupFingerprint() string {
+	sum := fnv.New64a()
+
+	if m.GroupKey != "" {
+		sum.Write([]byte(m.GroupKey))
+		sum.Write([]byte{255})
+	} else {
+		sum.Write([]byte(m.Receiver))
+		sum.Write([]byte{255})
+		writeSorted(sum, m.GroupLabels)
+	}
+
+	return fmt.Sprintf("%016x", sum.Sum64())
 }
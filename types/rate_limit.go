@@ -0,0 +1,38 @@
+package types
+
+import "time"
+
+// TokenBucket is the state of the rate limit (the bucket that holds up to
+// burst tokens and gets refilled with burst tokens per period).  The zero
+// value is the full bucket.
+type TokenBucket struct {
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Take refills the bucket as of now and takes a token from it.  It returns
+// false if there is none left.
+func (b *TokenBucket) Take(burst int, period time.Duration, now time.Time) bool {
+	b.refill(burst, period, now)
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
+
+// Return refills the bucket as of now and puts the token taken earlier back
+// into it (e.g. when the other limits did not let the alert through).
+func (b *TokenBucket) Return(burst int, period time.Duration, now time.Time) {
+	b.refill(burst, period, now)
+	b.Tokens = min(float64(burst), b.Tokens+1)
+}
+
+func (b *TokenBucket) refill(burst int, period time.Duration, now time.Time) {
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = float64(burst)
+	} else if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens = min(float64(burst), b.Tokens+float64(burst)*elapsed.Seconds()/period.Seconds())
+	}
+	b.UpdatedAt = now
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	const (
+		burst  = 2
+		period = time.Minute
+	)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	type step struct {
+		after  time.Duration // since the start
+		take   bool          // take (or return) the token
+		want   bool          // only for take
+		tokens float64       // left in the bucket afterwards
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "full when zero",
+			steps: []step{
+				{take: true, want: true, tokens: 1},
+				{take: true, want: true, tokens: 0},
+				{take: true, want: false, tokens: 0},
+			},
+		},
+		{
+			name: "refills over time",
+			steps: []step{
+				{take: true, want: true, tokens: 1},
+				{take: true, want: true, tokens: 0},
+				{after: 15 * time.Second, take: true, want: false, tokens: 0.5},
+				{after: 30 * time.Second, take: true, want: true, tokens: 0},
+			},
+		},
+		{
+			name: "refills up to burst",
+			steps: []step{
+				{take: true, want: true, tokens: 1},
+				{after: time.Hour, take: true, want: true, tokens: 1},
+			},
+		},
+		{
+			name: "time going back does not drain",
+			steps: []step{
+				{after: time.Minute, take: true, want: true, tokens: 1},
+				{after: 0, take: true, want: true, tokens: 0},
+			},
+		},
+		{
+			name: "returns the token",
+			steps: []step{
+				{take: true, want: true, tokens: 1},
+				{take: true, want: true, tokens: 0},
+				{take: false, tokens: 1},
+				{take: true, want: true, tokens: 0},
+			},
+		},
+		{
+			name: "returns up to burst",
+			steps: []step{
+				{take: true, want: true, tokens: 1},
+				{after: 30 * time.Second, take: false, tokens: 2},
+				{take: false, tokens: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &TokenBucket{}
+			for idx, s := range tt.steps {
+				now := start.Add(s.after)
+				if s.take {
+					if got := b.Take(burst, period, now); got != s.want {
+						t.Errorf("step %d: want take %v, got %v", idx, s.want, got)
+					}
+				} else {
+					b.Return(burst, period, now)
+				}
+				if b.Tokens != s.tokens {
+					t.Errorf("step %d: want %v tokens, got %v", idx, s.tokens, b.Tokens)
+				}
+			}
+		})
+	}
+}
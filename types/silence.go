@@ -0,0 +1,33 @@
+package types
+
+import "time"
+
+const (
+	SilenceStateActive  = "active"
+	SilenceStateExpired = "expired"
+	SilenceStatePending = "pending"
+)
+
+// Silence mutes the alerts that match all of its matchers (prometheus-style
+// ones, e.g. `severity=~"info|none"`) between its start and its end.
+type Silence struct {
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	EndsAt    time.Time `json:"endsAt"`
+	ID        string    `json:"id"`
+	Matchers  []string  `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+}
+
+// State returns the state of the silence at the moment.
+func (s *Silence) State(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilenceStatePending
+	case now.Before(s.EndsAt):
+		return SilenceStateActive
+	default:
+		return SilenceStateExpired
+	}
+}
@@ -0,0 +1,178 @@
+package types
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	ThreadActionAcknowledge = "acknowledge"
+	ThreadActionResolve     = "resolve"
+	ThreadActionSilence     = "silence"
+)
+
+// Thread is the state of the thread that tracks the alert.
+type Thread struct {
+	TS string
+
+	// ID and Topic are the key of the thread in the db.
+	ID    string
+	Topic string
+
+	// Alert is the latest alert that was published into the thread.
+	Alert *Alert
+
+	FiringCount   int
+	ResolvedCount int
+	LastChangeAt  time.Time
+	StartedAt     time.Time
+
+	// StatusChangedAt is when the alert last changed its status (from firing
+	// to resolved or back).  Unlike LastChangeAt, the repeated notifications
+	// of the same status do not move it.
+	StatusChangedAt time.Time
+
+	// Action is the latest action taken on the thread by the humans (e.g.
+	// acknowledge from slack).
+	Action *ThreadAction
+
+	// Flapping is the track of the status changes of the alert (nil if
+	// flapping detection is disabled or if there were none).
+	Flapping *Flapping
+
+	// RemindedAt is when the latest reminder about the alert that keeps
+	// firing was posted (zero if there was none).
+	RemindedAt time.Time
+
+	// Escalation is the progress of the escalation policy on the alert that
+	// nobody has acknowledged (nil if it was never escalated).
+	Escalation *Escalation
+}
+
+// Escalation is how far the alert of the thread has been escalated.
+type Escalation struct {
+	// Since is when the escalated alert started firing (the escalation
+	// starts over when the alert fires again).
+	Since time.Time `json:"since"`
+
+	// Level is the count of the steps of the policy taken so far.
+	Level int `json:"level"`
+
+	EscalatedAt time.Time `json:"escalatedAt"`
+}
+
+// Clone returns the copy of the escalation.
+func (e *Escalation) Clone() *Escalation {
+	if e == nil {
+		return nil
+	}
+	c := *e
+	return &c
+}
+
+// Equal tells whether both escalations are the same (or both are nil).
+func (e *Escalation) Equal(other *Escalation) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return e.Since.Equal(other.Since) &&
+		e.Level == other.Level &&
+		e.EscalatedAt.Equal(other.EscalatedAt)
+}
+
+// Flapping is the track of the status changes of the alert of the thread.
+type Flapping struct {
+	// Transitions are the times of the recent status changes (within the
+	// detection window).
+	Transitions []time.Time `json:"transitions,omitempty"`
+
+	LastTransitionAt time.Time `json:"lastTransitionAt"`
+
+	// Since is when the alert started flapping (zero if it is not).
+	Since time.Time `json:"since,omitempty"`
+
+	// Changes and Suppressed are the counts of the status changes and of
+	// the suppressed replies since the alert started flapping.
+	Changes    int `json:"changes,omitempty"`
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Clone returns the deep copy of the track.
+func (f *Flapping) Clone() *Flapping {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.Transitions = slices.Clone(f.Transitions)
+	return &c
+}
+
+// IsFlapping tells whether the alert of the thread is flapping.
+func (f *Flapping) IsFlapping() bool {
+	return f != nil && !f.Since.IsZero()
+}
+
+// ThreadAction is what the user did with the thread (e.g. by clicking the
+// buttons of its root message in slack).
+type ThreadAction struct {
+	At       time.Time `json:"at"`
+	Type     string    `json:"type"`
+	UserID   string    `json:"userId"`
+	UserName string    `json:"userName"`
+
+	// SilenceID, SilenceURL, and SilencedUntil are only set for silence
+	// action (the url only if the silence was created in alertmanager).
+	SilenceID     string    `json:"silenceId,omitempty"`
+	SilenceURL    string    `json:"silenceUrl,omitempty"`
+	SilencedUntil time.Time `json:"silencedUntil,omitempty"`
+}
+
+// ThreadRef points at the thread (e.g. from the value of slack's button).
+type ThreadRef struct {
+	Destination string `json:"d"`
+	ID          string `json:"i"`
+	Topic       string `json:"t"`
+}
+
+// Resolved tells whether the alert of the thread is resolved (either by
+// alertmanager, or manually since its status last changed).
+func (t *Thread) Resolved() bool {
+	if t.Status() == AlertStatusResolved {
+		return true
+	}
+	return t.Action != nil &&
+		t.Action.Type == ThreadActionResolve &&
+		!t.Action.At.Before(t.StatusChangedAt)
+}
+
+// IsFlapping tells whether the alert of the thread is flapping.
+func (t *Thread) IsFlapping() bool {
+	return t.Flapping.IsFlapping()
+}
+
+// Acknowledged tells whether someone has acted on the thread (acknowledged,
+// silenced, or resolved it) since its alert last changed its status.
+func (t *Thread) Acknowledged() bool {
+	return t.Action != nil && !t.Action.At.Before(t.StatusChangedAt)
+}
+
+// Status returns the status of the latest alert in the thread.
+func (t *Thread) Status() string {
+	if t.Alert == nil {
+		return ""
+	}
+	return t.Alert.Status
+}
+
+// Duration returns for how long the alert has been firing (or for how long
+// it had been firing if it is resolved).  It is zero if the start of the
+// thread is not known (e.g. the thread predates tracking it).
+func (t *Thread) Duration(now time.Time) time.Duration {
+	if t.StartedAt.IsZero() {
+		return 0
+	}
+	if t.Status() == AlertStatusResolved {
+		return t.StatusChangedAt.Sub(t.StartedAt)
+	}
+	return now.Sub(t.StartedAt)
+}
@@ -0,0 +1,161 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThreadState(t *testing.T) {
+	startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	changedAt := startedAt.Add(time.Hour)
+	now := startedAt.Add(3 * time.Hour)
+
+	firing := &Alert{Status: AlertStatusFiring}
+	resolved := &Alert{Status: AlertStatusResolved}
+	action := func(typ string, at time.Time) *ThreadAction {
+		return &ThreadAction{At: at, Type: typ, UserID: "U1"}
+	}
+
+	tests := []struct {
+		name         string
+		thread       Thread
+		acknowledged bool
+		resolved     bool
+		duration     time.Duration
+	}{
+		{
+			name:     "no alert",
+			thread:   Thread{StartedAt: startedAt},
+			duration: 3 * time.Hour,
+		},
+		{
+			name:     "firing",
+			thread:   Thread{Alert: firing, StartedAt: startedAt, StatusChangedAt: changedAt},
+			duration: 3 * time.Hour,
+		},
+		{
+			name:     "resolved",
+			thread:   Thread{Alert: resolved, StartedAt: startedAt, StatusChangedAt: changedAt},
+			resolved: true,
+			duration: time.Hour,
+		},
+		{
+			name:     "unknown start",
+			thread:   Thread{Alert: firing, StatusChangedAt: changedAt},
+			duration: 0,
+		},
+		{
+			name: "acknowledged",
+			thread: Thread{
+				Action:          action(ThreadActionAcknowledge, changedAt.Add(time.Minute)),
+				Alert:           firing,
+				StartedAt:       startedAt,
+				StatusChangedAt: changedAt,
+			},
+			acknowledged: true,
+			duration:     3 * time.Hour,
+		},
+		{
+			name: "acknowledged at the change",
+			thread: Thread{
+				Action:          action(ThreadActionAcknowledge, changedAt),
+				Alert:           firing,
+				StartedAt:       startedAt,
+				StatusChangedAt: changedAt,
+			},
+			acknowledged: true,
+			duration:     3 * time.Hour,
+		},
+		{
+			name: "acknowledged before it fired again",
+			thread: Thread{
+				Action:          action(ThreadActionAcknowledge, changedAt.Add(-time.Minute)),
+				Alert:           firing,
+				StartedAt:       startedAt,
+				StatusChangedAt: changedAt,
+			},
+			duration: 3 * time.Hour,
+		},
+		{
+			name: "resolved manually",
+			thread: Thread{
+				Action:          action(ThreadActionResolve, changedAt.Add(time.Minute)),
+				Alert:           firing,
+				StartedAt:       startedAt,
+				StatusChangedAt: changedAt,
+			},
+			acknowledged: true,
+			resolved:     true,
+			duration:     3 * time.Hour,
+		},
+		{
+			name: "resolved manually before it fired again",
+			thread: Thread{
+				Action:          action(ThreadActionResolve, changedAt.Add(-time.Minute)),
+				Alert:           firing,
+				StartedAt:       startedAt,
+				StatusChangedAt: changedAt,
+			},
+			duration: 3 * time.Hour,
+		},
+		{
+			name: "silenced",
+			thread: Thread{
+				Action:          action(ThreadActionSilence, changedAt.Add(time.Minute)),
+				Alert:           firing,
+				StartedAt:       startedAt,
+				StatusChangedAt: changedAt,
+			},
+			acknowledged: true,
+			duration:     3 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.thread.Acknowledged(); got != tt.acknowledged {
+				t.Errorf("want acknowledged %v, got %v", tt.acknowledged, got)
+			}
+			if got := tt.thread.Resolved(); got != tt.resolved {
+				t.Errorf("want resolved %v, got %v", tt.resolved, got)
+			}
+			if got := tt.thread.Duration(now); got != tt.duration {
+				t.Errorf("want duration %v, got %v", tt.duration, got)
+			}
+		})
+	}
+}
+
+func TestEscalationEqual(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := &Escalation{EscalatedAt: since.Add(time.Hour), Level: 1, Since: since}
+
+	tests := []struct {
+		name  string
+		a, b  *Escalation
+		equal bool
+	}{
+		{name: "both nil", equal: true},
+		{name: "one nil", a: e},
+		{name: "clone", a: e, b: e.Clone(), equal: true},
+		{
+			name:  "other location",
+			a:     e,
+			b:     &Escalation{EscalatedAt: e.EscalatedAt.Local(), Level: 1, Since: since.Local()},
+			equal: true,
+		},
+		{name: "other level", a: e, b: &Escalation{EscalatedAt: e.EscalatedAt, Level: 2, Since: since}},
+		{name: "fired again", a: e, b: &Escalation{EscalatedAt: e.EscalatedAt, Level: 1, Since: since.Add(time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Equal(tt.b); got != tt.equal {
+				t.Errorf("want equal %v, got %v", tt.equal, got)
+			}
+			if got := tt.b.Equal(tt.a); got != tt.equal {
+				t.Errorf("want symmetric equal %v, got %v", tt.equal, got)
+			}
+		})
+	}
+}